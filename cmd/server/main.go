@@ -2,12 +2,15 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"os/signal"
+	"slices"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -16,19 +19,47 @@ import (
 	"github.com/KhanSufiyanMirza/evm-indexer-go/internal/indexer"
 	"github.com/KhanSufiyanMirza/evm-indexer-go/internal/metrics"
 	"github.com/KhanSufiyanMirza/evm-indexer-go/internal/storage"
-	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
 )
 
 const (
 	RpcUrl                = "RPC_URL"
+	RpcUrls               = "RPC_URLS"
 	MetricsPort           = "METRICS_PORT"
 	StartBlock            = "START_BLOCK"
 	SafeBlockDepth        = "SAFE_BLOCK_DEPTH"
 	defaultSafeBlockDepth = 12
 	IngestionBlockDepth   = "INGESTION_BLOCK_DEPTH"
+	IngestionBlockTag     = "INGESTION_BLOCK_TAG"
 	Continuous            = "CONTINUOUS"
 	BlockPollInterval     = "BLOCK_POLL_INTERVAL"
 	defaultPollInterval   = 12 * time.Second // ~Ethereum block time
+	RpcBatchSize          = "RPC_BATCH_SIZE"
+	RpcMaxTries           = "RPC_MAX_TRIES"
+	RpcCacheSize          = "RPC_CACHE_SIZE"
+	RpcRequestsPerSecond  = "RPC_REQUESTS_PER_SECOND"
+	RpcComputeUnitsPerSec = "RPC_COMPUTE_UNITS_PER_SECOND"
+	RpcMethodCosts        = "RPC_METHOD_COSTS"
+	RpcVerifyUrls         = "RPC_VERIFY_URLS"
+	RpcVerifyQuorum       = "RPC_VERIFY_QUORUM"
+	RpcVerifyOnMismatch   = "RPC_VERIFY_ON_MISMATCH"
+	RpcRecordDir          = "RPC_RECORD_DIR"
+	RpcReplayDir          = "RPC_REPLAY_DIR"
+	IndexReceipts         = "INDEX_RECEIPTS"
+	IndexInternalTxs      = "INDEX_INTERNAL_TRANSFERS"
+	RecentBlocks          = "RECENT_BLOCKS"
+	FetchWorkers          = "FETCH_WORKERS"
+	BackfillRange         = "BACKFILL_RANGE"
+	IndexPendingTxs       = "INDEX_PENDING_TRANSACTIONS"
+	PendingTxTimeout      = "PENDING_TX_TIMEOUT"
+	defaultPendingTimeout = time.Hour
+	RpcBreakerFailures    = "RPC_BREAKER_FAILURE_THRESHOLD"
+	RpcBreakerSuccesses   = "RPC_BREAKER_SUCCESS_THRESHOLD"
+	RpcBreakerOpenTimeout = "RPC_BREAKER_OPEN_TIMEOUT"
+	AbiDir                = "ABI_DIR"
+	AbiContracts          = "ABI_CONTRACTS"
 )
 
 func main() {
@@ -54,31 +85,156 @@ func main() {
 
 	storageStore := storage.NewStore(sqlcStore)
 
-	// 2. Setup Eth Client
-	rawurl, exist := os.LookupEnv(RpcUrl)
-	if !exist || rawurl == "" {
-		slog.Warn("RPC_URL is missing, continuing with default RPC")
-		rawurl = "https://eth.llamarpc.com"
+	// 2. Setup Eth Clients (none when replaying recorded fixtures)
+	replayDir := os.Getenv(RpcReplayDir)
+	var endpoints []gateway.Endpoint
+	if replayDir == "" {
+		endpoints, err = gateway.DialEndpoints(getRPCURLs())
+		if err != nil {
+			slog.Error("Failed to dial RPC", "error", err)
+			os.Exit(1)
+		}
 	}
-
-	client, err := ethclient.Dial(rawurl)
-	if err != nil {
-		slog.Error("Failed to dial RPC", "error", err)
-		os.Exit(1)
+	defer func() {
+		for _, ep := range endpoints {
+			ep.Client.Close()
+		}
+	}()
+	for _, ep := range endpoints {
+		slog.Info("RPC endpoint configured", "endpoint", ep.Name)
+	}
+	// Refuse to index another chain than the one the database holds (replayed
+	// fixtures were recorded from an endpoint that was checked).
+	if replayDir == "" {
+		chain, err := bindChain(context.Background(), storageStore, endpoints)
+		if err != nil {
+			if errors.Is(err, storage.ErrChainMismatch) || errors.Is(err, gateway.ErrChainMismatch) {
+				slog.Error("ALERT: Refusing to start on another chain", "error", err)
+			} else {
+				slog.Error("Failed to verify chain identity", "error", err)
+			}
+			os.Exit(1)
+		}
+		slog.Info("Chain identity verified", "chainId", chain.ChainID, "genesisHash", chain.GenesisHash)
 	}
-	defer client.Close()
 	ingestionBlockDepth, err := getIngestionBlockDepth()
 	if err != nil {
 		slog.Error("Failed to get block depth", "error", err)
 		os.Exit(1)
 	}
 	slog.Info("block depth configured for ingestion", "depth", ingestionBlockDepth)
+	ingestionTag, useIngestionTag := getIngestionBlockTag()
 
 	// 3. Setup Fetcher
-	fetcher := gateway.NewBlockFetcher(client)
+	rateLimit := getRPCRateLimit()
+	if rateLimit.RequestsPerSecond > 0 || rateLimit.ComputeUnitsPerSecond > 0 {
+		slog.Info("RPC rate limit configured", "requestsPerSecond", rateLimit.RequestsPerSecond, "computeUnitsPerSecond", rateLimit.ComputeUnitsPerSecond)
+	}
+	// Every event handler's logs are fetched with one query per block and
+	// set of contracts.
+	registry := indexer.DefaultRegistry()
+	contracts, err := getABIContracts()
+	if err != nil {
+		slog.Error("Failed to load contract ABIs", "error", err)
+		os.Exit(1)
+	}
+	if len(contracts) > 0 {
+		slog.Info("ABI event decoding enabled", "contracts", len(contracts))
+		handler, err := indexer.NewABIHandler(contracts)
+		if err == nil {
+			err = registry.Register(handler)
+		}
+		if err != nil {
+			slog.Error("Failed to register ABI handler", "error", err)
+			os.Exit(1)
+		}
+	}
+	fetcherOpts := []gateway.Option{
+		gateway.WithLogFilters(registry.Filters()...),
+		gateway.WithBatchSize(getRPCBatchSize()),
+		gateway.WithRateLimit(rateLimit),
+		gateway.WithInterceptors(getRPCInterceptors(true)...),
+	}
+	// Verifiers get their own interceptors without the cache: answered from
+	// the primary's responses, they would only ever agree with it.
+	verifierOpts := append(slices.Clone(fetcherOpts), gateway.WithInterceptors(getRPCInterceptors(false)...))
+	var fetcher gateway.BlockFetcher
+	if replayDir != "" {
+		slog.Info("Replaying recorded RPC fixtures", "dir", replayDir)
+		fetcher, err = gateway.NewReplayFetcher(replayDir)
+	} else {
+		fetcher, err = gateway.NewMultiBlockFetcher(endpoints, fetcherOpts...)
+	}
+	if err != nil {
+		slog.Error("Failed to create block fetcher", "error", err)
+		os.Exit(1)
+	}
+	if recordDir := os.Getenv(RpcRecordDir); recordDir != "" {
+		slog.Info("Recording RPC responses as fixtures", "dir", recordDir)
+		fetcher, err = gateway.NewRecordingFetcher(fetcher, recordDir)
+		if err != nil {
+			slog.Error("Failed to set up RPC recording", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	// Optional: cross-check block data against independent providers
+	if verifyURLs := getURLList(RpcVerifyUrls); len(verifyURLs) > 0 {
+		verifiers, err := gateway.DialEndpoints(verifyURLs)
+		if err != nil {
+			slog.Error("Failed to dial verifier RPC", "error", err)
+			os.Exit(1)
+		}
+		defer func() {
+			for _, ep := range verifiers {
+				ep.Client.Close()
+			}
+		}()
+		if replayDir == "" {
+			// Verifiers on another chain would disagree on every block.
+			_, err := gateway.GetEndpointsChain(context.Background(), append([]gateway.Endpoint{endpoints[0]}, verifiers...))
+			if err != nil {
+				slog.Error("ALERT: Verifier RPC is on another chain", "error", err)
+				os.Exit(1)
+			}
+		}
+		quorumCfg := getQuorumConfig(storageStore)
+		fetcher, err = gateway.NewQuorumFetcher(fetcher, verifiers, quorumCfg, verifierOpts...)
+		if err != nil {
+			slog.Error("Failed to set up quorum verification", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	// Stop calling a provider that is down, the indexer pauses until it is back
+	if breakerCfg, enabled := getBreakerConfig(); enabled {
+		breaker := gateway.NewCircuitBreaker(breakerCfg)
+		fetcher = gateway.Intercept(fetcher, breaker.Interceptor())
+	} else {
+		slog.Info("RPC circuit breaker disabled")
+	}
+
+	// ingestionEnd returns the last block to ingest: the block INGESTION_BLOCK_TAG
+	// points to, or the tip minus INGESTION_BLOCK_DEPTH when no tag is set or the
+	// node does not serve it.
+	ingestionEnd := func(latest uint64) int64 {
+		if useIngestionTag {
+			tagged, err := fetcher.GetTaggedBlockNumber(context.Background(), ingestionTag)
+			if err == nil {
+				return int64(tagged)
+			}
+			if errors.Is(err, gateway.ErrBlockTagUnsupported) {
+				slog.Warn("Node does not serve the ingestion block tag, using block depth", "tag", ingestionTag, "depth", ingestionBlockDepth)
+				useIngestionTag = false
+			} else {
+				slog.Warn("Failed to get ingestion block tag, using block depth", "tag", ingestionTag, "error", err)
+			}
+		}
+		return int64(latest) - int64(ingestionBlockDepth)
+	}
 
 	// 4. Determine Range
-	latestBlockNumberOnchain, err := fetcher.GetBlockNumberWithRetry(context.Background())
+	latestBlockNumberOnchain, err := fetcher.GetBlockNumber(context.Background())
 	if err != nil {
 		slog.Error("Failed to get latest block", "error", err)
 		os.Exit(1)
@@ -102,7 +258,7 @@ func main() {
 	}
 
 	start := processedLastBlock + 1
-	end := int64(latestBlockNumberOnchain - ingestionBlockDepth)
+	end := ingestionEnd(latestBlockNumberOnchain)
 	slog.Info("Indexing range determined", "lastProcessed", processedLastBlock, "latestOnchain", latestBlockNumberOnchain, "diff", latestBlockNumberOnchain-uint64(processedLastBlock))
 
 	runContinuous := getContinuous()
@@ -114,7 +270,32 @@ func main() {
 	slog.Info("---------------------------------------------")
 
 	// 5. Run Indexer
-	idx := indexer.NewIndexer(fetcher, storageStore)
+	safeBlockDepth, err := getSafeBlockDepth()
+	if err != nil {
+		slog.Error("Failed to get safe block depth", "error", err)
+		os.Exit(1)
+	}
+	indexerOpts := []indexer.Option{indexer.WithRegistry(registry)}
+	if getBoolEnv(IndexReceipts) {
+		slog.Info("Receipt indexing enabled")
+		indexerOpts = append(indexerOpts, indexer.WithReceipts())
+	}
+	if getBoolEnv(IndexInternalTxs) {
+		slog.Info("Internal transfer indexing enabled")
+		indexerOpts = append(indexerOpts, indexer.WithInternalTransfers())
+	}
+	if n := getRecentBlocks(); n > 0 {
+		indexerOpts = append(indexerOpts, indexer.WithRecentBlocks(n))
+	}
+	if n := getFetchWorkers(); n > 0 {
+		slog.Info("Fetch workers configured", "workers", n)
+		indexerOpts = append(indexerOpts, indexer.WithFetchWorkers(n))
+	}
+	if n := getBackfillRange(); n > 0 {
+		slog.Info("Range-based backfill enabled below the finalized block", "rangeSize", n)
+		indexerOpts = append(indexerOpts, indexer.WithBackfill(n, safeBlockDepth))
+	}
+	idx := indexer.NewIndexer(fetcher, storageStore, indexerOpts...)
 
 	// We use signal.NotifyContext to handle graceful shutdown in background it
 	// spawns a new goroutine to wait for a signal and returns a context that is
@@ -122,43 +303,58 @@ func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 	// run finality modelling in background
-	safeBlockDepth, err := getSafeBlockDepth()
-	if err != nil {
-		slog.Error("Failed to get safe block depth", "error", err)
-		os.Exit(1)
-	}
 	go func() {
 		err = idx.RunFinalizer(ctx, safeBlockDepth)
 		if err != nil {
 			slog.Error("Finalizer stopped with error", "error", err)
 		}
 	}()
+	// Optional: follow the mempool, needs a ws or ipc endpoint
+	if getBoolEnv(IndexPendingTxs) {
+		watcher, err := gateway.NewPendingTxWatcher(endpoints, pollInterval)
+		if err != nil {
+			slog.Error("Cannot index pending transactions", "error", err)
+			os.Exit(1)
+		}
+		pendingTimeout := getPendingTxTimeout()
+		slog.Info("Pending transaction indexing enabled", "timeout", pendingTimeout)
+		mempool := indexer.NewMempool(watcher, fetcher, storageStore, pendingTimeout)
+		go func() {
+			if err := mempool.Run(ctx); err != nil {
+				slog.Error("Mempool stopped with error", "error", err)
+			}
+		}()
+	}
 	// run indexer
 	startTime := time.Now()
 	lastProcessedBlock, err := idx.Run(ctx, start, end)
 	if err != nil {
 		slog.Error("Indexer stopped with error", "error", err)
+		if errors.Is(err, gateway.ErrQuorumMismatch) && runContinuous {
+			// Retried in continuous mode, providers may agree again.
+			slog.Error("ALERT: Providers disagree on block data, retrying on the next head", "lastProcessed", lastProcessedBlock)
+			err = nil
+		}
 	}
-	slog.Info("Indexing complete", "blocksIndexed", lastProcessedBlock-start+1, "duration", time.Since(startTime))
+	indexed, elapsed := lastProcessedBlock-start+1, time.Since(startTime)
+	slog.Info("Indexing complete", "blocksIndexed", indexed, "duration", elapsed, "blocksPerSecond", float64(indexed)/elapsed.Seconds())
 
-	// 6. Continuous mode: keep polling for new blocks until shutdown
+	// 6. Continuous mode: follow new heads until shutdown
 	if runContinuous && err == nil {
-		slog.Info("Entering continuous mode; polling for new blocks")
+		slog.Info("Entering continuous mode; waiting for new heads")
+		headTracker := gateway.NewHeadTracker(fetcher, endpoints, pollInterval)
+		go headTracker.Run(ctx)
 		for {
+			var latest uint64
 			select {
 			case <-ctx.Done():
 				slog.Info("Shutdown signal received, exiting continuous mode")
 				return
-			case <-time.After(pollInterval):
-			}
-			latest, err := fetcher.GetBlockNumberWithRetry(ctx)
-			if err != nil {
-				slog.Error("Failed to get latest block in continuous mode", "error", err)
-				continue
+			case latest = <-headTracker.Heads():
 			}
 			metrics.ChainTipHeight.Set(float64(latest))
 			start = lastProcessedBlock + 1
-			end = int64(latest) - int64(ingestionBlockDepth)
+			end = ingestionEnd(latest)
 			if start > end {
 				slog.Debug("No new blocks to index", "lastProcessed", lastProcessedBlock, "latest", latest)
 				continue
@@ -167,6 +363,10 @@ func main() {
 			lastProcessedBlock, err = idx.Run(ctx, start, end)
 			if err != nil {
 				slog.Error("Indexer stopped with error in continuous mode", "error", err)
+				if errors.Is(err, gateway.ErrQuorumMismatch) {
+					// Nothing unverified was saved, the block is tried again on the next head.
+					slog.Error("ALERT: Providers disagree on block data, retrying on the next head", "lastProcessed", lastProcessedBlock)
+				}
 				continue
 			}
 			slog.Info("Caught up", "lastProcessed", lastProcessedBlock, "blocksIndexed", lastProcessedBlock-start+1)
@@ -174,6 +374,86 @@ func main() {
 	}
 }
 
+// getRPCURLs returns the RPC endpoints in order of preference. RPC_URLS takes a
+// comma-separated list for failover; otherwise the single RPC_URL is used.
+func getRPCURLs() []string {
+	if urls := getURLList(RpcUrls); len(urls) > 0 {
+		return urls
+	}
+	rawurl, exist := os.LookupEnv(RpcUrl)
+	if !exist || rawurl == "" {
+		slog.Warn("RPC_URL is missing, continuing with default RPC")
+		rawurl = "https://eth.llamarpc.com"
+	}
+	return []string{rawurl}
+}
+
+// bindChain checks that every endpoint serves the chain the database holds,
+// binding the database to the endpoints' chain on its first run.
+func bindChain(ctx context.Context, store *storage.Store, endpoints []gateway.Endpoint) (gateway.ChainIdentity, error) {
+	chain, err := gateway.GetEndpointsChain(ctx, endpoints)
+	if err != nil {
+		return gateway.ChainIdentity{}, err
+	}
+	if !chain.ChainID.IsInt64() {
+		return gateway.ChainIdentity{}, fmt.Errorf("chain ID %s out of range", chain.ChainID)
+	}
+	bound, err := store.BindChain(ctx, chain.ChainID.Int64(), chain.GenesisHash.Hex())
+	if err != nil {
+		return gateway.ChainIdentity{}, err
+	}
+	if bound {
+		slog.Info("Database bound to chain", "chainId", chain.ChainID, "genesisHash", chain.GenesisHash)
+	}
+	return chain, nil
+}
+
+// getURLList splits the comma-separated env name, dropping empty entries.
+func getURLList(name string) []string {
+	s, exist := os.LookupEnv(name)
+	if !exist || s == "" {
+		return nil
+	}
+	var urls []string
+	for _, u := range strings.Split(s, ",") {
+		if u = strings.TrimSpace(u); u != "" {
+			urls = append(urls, u)
+		}
+	}
+	return urls
+}
+
+// getQuorumConfig reads the verification settings. Discrepancies are always
+// recorded in block_discrepancies; RPC_VERIFY_ON_MISMATCH decides whether a
+// failed quorum also halts ingestion ("halt", the default) or not ("record").
+func getQuorumConfig(store *storage.Store) gateway.QuorumConfig {
+	cfg := gateway.QuorumConfig{
+		Policy: gateway.MismatchPolicy(os.Getenv(RpcVerifyOnMismatch)),
+		Recorder: gateway.DiscrepancyRecorderFunc(func(ctx context.Context, d gateway.Discrepancy) error {
+			observed, err := json.Marshal(d.Observed)
+			if err != nil {
+				return err
+			}
+			return store.SaveBlockDiscrepancy(ctx, sqlc.CreateBlockDiscrepancyParams{
+				BlockNumber:   int64(d.BlockNumber),
+				Kind:          d.Kind,
+				Expected:      d.Expected,
+				Observed:      observed,
+				QuorumReached: d.QuorumReached,
+			})
+		}),
+	}
+	if s, exist := os.LookupEnv(RpcVerifyQuorum); exist && s != "" {
+		quorum, err := strconv.Atoi(s)
+		if err != nil {
+			slog.Warn("Invalid RPC_VERIFY_QUORUM, using majority", "value", s)
+		} else {
+			cfg.Quorum = quorum
+		}
+	}
+	return cfg
+}
+
 func getStartBlock() (uint64, error) {
 	startBlockStr, exist := os.LookupEnv(StartBlock)
 	if !exist {
@@ -199,8 +479,29 @@ func getIngestionBlockDepth() (uint64, error) {
 	return blockDepth, nil
 }
 
+// getIngestionBlockTag returns the block tag ingestion stops at, "safe" or
+// "finalized", if one is configured.
+func getIngestionBlockTag() (rpc.BlockNumber, bool) {
+	switch tag := strings.ToLower(os.Getenv(IngestionBlockTag)); tag {
+	case "":
+		return 0, false
+	case "safe":
+		return rpc.SafeBlockNumber, true
+	case "finalized":
+		return rpc.FinalizedBlockNumber, true
+	default:
+		slog.Warn("Invalid INGESTION_BLOCK_TAG, using block depth", "value", tag)
+		return 0, false
+	}
+}
+
 func getContinuous() bool {
-	s, _ := os.LookupEnv(Continuous)
+	return getBoolEnv(Continuous)
+}
+
+// getBoolEnv reports whether env name is set to a true value.
+func getBoolEnv(name string) bool {
+	s, _ := os.LookupEnv(name)
 	switch s {
 	case "1", "true", "yes", "on":
 		return true
@@ -225,6 +526,217 @@ func getBlockPollInterval() time.Duration {
 	return d
 }
 
+// getPendingTxTimeout returns how long a transaction may stay pending before
+// it is considered dropped.
+func getPendingTxTimeout() time.Duration {
+	s, exist := os.LookupEnv(PendingTxTimeout)
+	if !exist || s == "" {
+		return defaultPendingTimeout
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		slog.Warn("Invalid PENDING_TX_TIMEOUT, using default", "value", s, "default", defaultPendingTimeout)
+		return defaultPendingTimeout
+	}
+	return d
+}
+
+func getRPCBatchSize() int {
+	s, exist := os.LookupEnv(RpcBatchSize)
+	if !exist || s == "" {
+		return gateway.DefaultBatchSize
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		slog.Warn("Invalid RPC_BATCH_SIZE, using default", "value", s, "default", gateway.DefaultBatchSize)
+		return gateway.DefaultBatchSize
+	}
+	return n
+}
+
+// getRPCInterceptors returns the interceptor chain of the RPC calls. With
+// cache and RPC_CACHE_SIZE set, responses to calls by block hash, which a
+// reorg cannot change, are cached in front of it.
+func getRPCInterceptors(cache bool) []gateway.Interceptor {
+	interceptors := []gateway.Interceptor{gateway.Logging(), gateway.Metrics(), gateway.Retry(getRPCRetryPolicy())}
+	if !cache {
+		return interceptors
+	}
+	s, exist := os.LookupEnv(RpcCacheSize)
+	if !exist || s == "" {
+		return interceptors
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		slog.Warn("Invalid RPC_CACHE_SIZE, not caching", "value", s)
+		return interceptors
+	}
+	if n == 0 {
+		return interceptors
+	}
+	cached := gateway.PerMethod(nil, map[string]gateway.Interceptor{
+		gateway.MethodGetBlockReceipts:     gateway.Cache(n, 0),
+		gateway.MethodGetInternalTransfers: gateway.Cache(n, 0),
+	})
+	return append([]gateway.Interceptor{cached}, interceptors...)
+}
+
+// getRecentBlocks returns the configured size of the recent block cache, or 0
+// to keep the indexer's default.
+func getRecentBlocks() int {
+	s, exist := os.LookupEnv(RecentBlocks)
+	if !exist || s == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		slog.Warn("Invalid RECENT_BLOCKS, using default", "value", s)
+		return 0
+	}
+	return n
+}
+
+// getBreakerConfig reads the RPC circuit breaker settings. A failure threshold
+// of 0 disables the breaker.
+func getBreakerConfig() (gateway.BreakerConfig, bool) {
+	cfg := gateway.DefaultBreakerConfig
+	for name, threshold := range map[string]*int{RpcBreakerFailures: &cfg.FailureThreshold, RpcBreakerSuccesses: &cfg.SuccessThreshold} {
+		s, exist := os.LookupEnv(name)
+		if !exist || s == "" {
+			continue
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			slog.Warn("Invalid value, using default", "env", name, "value", s, "default", *threshold)
+			continue
+		}
+		*threshold = n
+	}
+	if s, exist := os.LookupEnv(RpcBreakerOpenTimeout); exist && s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			slog.Warn("Invalid RPC_BREAKER_OPEN_TIMEOUT, using default", "value", s, "default", cfg.OpenTimeout)
+		} else {
+			cfg.OpenTimeout = d
+		}
+	}
+	return cfg, cfg.FailureThreshold > 0
+}
+
+// getFetchWorkers returns how many windows of blocks are fetched concurrently,
+// or 0 to keep the indexer's default.
+func getFetchWorkers() int {
+	s, exist := os.LookupEnv(FetchWorkers)
+	if !exist || s == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		slog.Warn("Invalid FETCH_WORKERS, using default", "value", s)
+		return 0
+	}
+	return n
+}
+
+// getBackfillRange returns how many finalized blocks are indexed per range
+// while backfilling, or 0 to index every block one at a time.
+func getBackfillRange() int {
+	s, exist := os.LookupEnv(BackfillRange)
+	if !exist || s == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		slog.Warn("Invalid BACKFILL_RANGE, backfill disabled", "value", s)
+		return 0
+	}
+	return n
+}
+
+// getABIContracts reads the contract ABIs in ABI_DIR and binds them to the
+// addresses in ABI_CONTRACTS, a comma-separated list of address=name where
+// name is an ABI file without .json. Nothing is decoded when ABI_DIR is unset.
+func getABIContracts() (map[common.Address]abi.ABI, error) {
+	dir, exist := os.LookupEnv(AbiDir)
+	if !exist || dir == "" {
+		return nil, nil
+	}
+	abis, err := indexer.LoadABIDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	contracts := make(map[common.Address]abi.ABI)
+	for _, pair := range strings.Split(os.Getenv(AbiContracts), ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		address, name, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		contract, found := abis[name]
+		if !ok || !common.IsHexAddress(address) || !found {
+			slog.Warn("Invalid ABI_CONTRACTS entry, ignoring", "entry", pair)
+			continue
+		}
+		contracts[common.HexToAddress(address)] = contract
+	}
+	if len(contracts) == 0 {
+		slog.Warn("ABI_DIR is set but ABI_CONTRACTS binds no contract, nothing is decoded", "dir", dir)
+	}
+	return contracts, nil
+}
+
+func getRPCRetryPolicy() gateway.RetryPolicy {
+	policy := gateway.DefaultRetryPolicy
+	s, exist := os.LookupEnv(RpcMaxTries)
+	if !exist || s == "" {
+		return policy
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		slog.Warn("Invalid RPC_MAX_TRIES, using default", "value", s, "default", policy.MaxTries)
+		return policy
+	}
+	policy.MaxTries = uint(n)
+	return policy
+}
+
+// getRPCRateLimit reads the per-endpoint client-side budget. RPC_METHOD_COSTS
+// overrides compute-unit costs as a comma-separated list of method=cost.
+func getRPCRateLimit() gateway.RateLimit {
+	limit := gateway.RateLimit{
+		RequestsPerSecond:     getFloatEnv(RpcRequestsPerSecond),
+		ComputeUnitsPerSecond: getFloatEnv(RpcComputeUnitsPerSec),
+	}
+	s, exist := os.LookupEnv(RpcMethodCosts)
+	if !exist || s == "" {
+		return limit
+	}
+	limit.MethodCosts = make(map[string]int)
+	for _, pair := range strings.Split(s, ",") {
+		method, costStr, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		cost, err := strconv.Atoi(costStr)
+		if !ok || err != nil || cost < 0 {
+			slog.Warn("Invalid RPC_METHOD_COSTS entry, ignoring", "entry", pair)
+			continue
+		}
+		limit.MethodCosts[method] = cost
+	}
+	return limit
+}
+
+// getFloatEnv returns the non-negative float in env name, or 0 when unset or invalid.
+func getFloatEnv(name string) float64 {
+	s, exist := os.LookupEnv(name)
+	if !exist || s == "" {
+		return 0
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || f < 0 {
+		slog.Warn("Invalid value, ignoring", "env", name, "value", s)
+		return 0
+	}
+	return f
+}
+
 func getSafeBlockDepth() (uint64, error) {
 
 	blockDepthStr, exist := os.LookupEnv(SafeBlockDepth)
@@ -0,0 +1,10 @@
+CREATE TABLE block_discrepancies (
+    id SERIAL PRIMARY KEY,
+    block_number BIGINT NOT NULL,
+    kind TEXT NOT NULL,
+    expected TEXT NOT NULL,
+    observed JSONB NOT NULL,
+    quorum_reached BOOLEAN NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_block_discrepancies_block_number ON block_discrepancies (block_number);
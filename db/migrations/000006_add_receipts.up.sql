@@ -0,0 +1,17 @@
+CREATE TABLE receipts (
+    tx_hash TEXT PRIMARY KEY,
+    block_number BIGINT NOT NULL,
+    block_hash TEXT NOT NULL,
+    tx_index INTEGER NOT NULL,
+    tx_type SMALLINT NOT NULL,
+    status SMALLINT NOT NULL,
+    gas_used BIGINT NOT NULL,
+    cumulative_gas_used BIGINT NOT NULL,
+    effective_gas_price NUMERIC,
+    contract_address TEXT,
+    logs JSONB NOT NULL,
+    is_canonical BOOLEAN DEFAULT TRUE,
+    reorg_detected_at TIMESTAMP NULL
+);
+CREATE INDEX IF NOT EXISTS idx_receipts_block_number ON receipts (block_number);
+CREATE INDEX IF NOT EXISTS idx_receipts_failed ON receipts (block_number) WHERE status = 0;
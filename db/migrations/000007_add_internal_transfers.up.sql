@@ -0,0 +1,14 @@
+CREATE TABLE internal_transfers (
+    tx_hash TEXT NOT NULL,
+    trace_address TEXT NOT NULL,
+    block_number BIGINT NOT NULL,
+    call_type TEXT NOT NULL,
+    from_address TEXT NOT NULL,
+    to_address TEXT NOT NULL,
+    value NUMERIC NOT NULL,
+    is_canonical BOOLEAN DEFAULT TRUE,
+    reorg_detected_at TIMESTAMP NULL,
+    PRIMARY KEY (tx_hash, trace_address)
+);
+CREATE INDEX IF NOT EXISTS idx_internal_transfers_block_number ON internal_transfers (block_number);
+CREATE INDEX IF NOT EXISTS idx_internal_transfers_to_address ON internal_transfers (to_address);
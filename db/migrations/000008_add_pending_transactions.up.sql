@@ -0,0 +1,18 @@
+CREATE TABLE pending_transactions (
+    tx_hash TEXT PRIMARY KEY,
+    from_address TEXT NOT NULL,
+    to_address TEXT,
+    nonce BIGINT NOT NULL,
+    value NUMERIC NOT NULL,
+    gas_fee_cap NUMERIC NOT NULL, -- gas price for legacy transactions
+    input BYTEA NOT NULL,
+    status TEXT NOT NULL DEFAULT 'PENDING',
+    block_number BIGINT,
+    replaced_by TEXT,
+    first_seen_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_pending_transactions_status ON pending_transactions (status, updated_at);
+CREATE INDEX IF NOT EXISTS idx_pending_transactions_sender ON pending_transactions (from_address, nonce);
+CREATE INDEX IF NOT EXISTS idx_pending_transactions_to_address ON pending_transactions (to_address) WHERE status = 'PENDING';
+CREATE INDEX IF NOT EXISTS idx_pending_transactions_block_number ON pending_transactions (block_number);
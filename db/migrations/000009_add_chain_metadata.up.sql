@@ -0,0 +1,8 @@
+-- The chain the database holds, bound on the first run. The single row is
+-- enforced by the id, which can only be TRUE.
+CREATE TABLE chain_metadata (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    chain_id BIGINT NOT NULL,
+    genesis_hash VARCHAR(66) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
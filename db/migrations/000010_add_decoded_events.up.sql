@@ -0,0 +1,18 @@
+-- Events decoded with contract ABIs, of any contract and event. args holds
+-- the event's arguments by name.
+CREATE TABLE decoded_events (
+    tx_hash TEXT NOT NULL,
+    log_index INTEGER NOT NULL,
+    block_number BIGINT NOT NULL,
+    block_hash TEXT NOT NULL,
+    tx_index INTEGER NOT NULL,
+    contract_address TEXT NOT NULL,
+    event_name TEXT NOT NULL,
+    event_signature TEXT NOT NULL,
+    args JSONB NOT NULL,
+    is_canonical BOOLEAN DEFAULT TRUE,
+    reorg_detected_at TIMESTAMP NULL,
+    PRIMARY KEY (tx_hash, log_index)
+);
+CREATE INDEX IF NOT EXISTS idx_decoded_events_block_number ON decoded_events (block_number);
+CREATE INDEX IF NOT EXISTS idx_decoded_events_contract_event ON decoded_events (contract_address, event_name);
@@ -0,0 +1,9 @@
+-- name: CreateBlockDiscrepancy :exec
+INSERT INTO block_discrepancies (block_number, kind, expected, observed, quorum_reached)
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: ListBlockDiscrepancies :many
+SELECT id, block_number, kind, expected, observed, quorum_reached, created_at
+FROM block_discrepancies
+ORDER BY block_number DESC
+LIMIT $1 OFFSET $2;
\ No newline at end of file
@@ -4,6 +4,11 @@ VALUES ($1, $2, $3, $4)
 ON CONFLICT (hash, number) DO UPDATE SET is_canonical = TRUE, reorg_detected_at = NULL
 RETURNING id, hash, number, parent_hash, timestamp;
 
+-- name: BatchCreateBlock :batchexec
+INSERT INTO blocks (hash, number, parent_hash, timestamp)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (hash, number) DO UPDATE SET is_canonical = TRUE, reorg_detected_at = NULL;
+
 -- name: GetBlockByID :one
 SELECT id, hash, number, parent_hash, timestamp
 FROM blocks
@@ -26,6 +31,17 @@ WHERE is_canonical = TRUE
 ORDER BY number DESC
 LIMIT $1 OFFSET $2;
 
+-- name: LockCanonicalBlock :one
+SELECT hash
+FROM blocks
+WHERE number = $1 AND is_canonical = TRUE
+FOR UPDATE;
+
+-- name: HasCanonicalBlockBelow :one
+SELECT EXISTS (
+    SELECT 1 FROM blocks WHERE number < $1 AND is_canonical = TRUE
+);
+
 -- name: UpdateBlock :one
 UPDATE blocks
 SET hash = $2, number = $3, parent_hash = $4, timestamp = $5
@@ -55,16 +71,26 @@ UPDATE blocks
 SET processed_at = NOW()
 WHERE number = $1 AND processed_at IS NULL;
 
+-- name: MarkBlockProcessedRange :exec
+UPDATE blocks
+SET processed_at = NOW()
+WHERE number >= @from_block AND number <= @to_block AND processed_at IS NULL;
+
 -- name: DeleteBlocksFromHeight :exec
 DELETE FROM blocks
 WHERE number > $1;
 
 -- name: MarkBlockReorgedRange :exec
 UPDATE blocks
-SET is_canonical = FALSE, reorg_detected_at = NOW()
+SET is_canonical = FALSE, reorg_detected_at = NOW(), status = 'PENDING'
 WHERE number > $1;
 
 -- name: MarkBlockFinalized :exec
 UPDATE blocks
 SET status = 'FINALIZED'
-WHERE number <= $1 AND is_canonical = TRUE AND status != 'FINALIZED';
\ No newline at end of file
+WHERE number <= $1 AND is_canonical = TRUE AND status != 'FINALIZED';
+
+-- name: MarkBlockSafe :exec
+UPDATE blocks
+SET status = 'SAFE'
+WHERE number <= $1 AND is_canonical = TRUE AND status = 'PENDING';
\ No newline at end of file
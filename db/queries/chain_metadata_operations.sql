@@ -0,0 +1,8 @@
+-- name: CreateChainMetadata :execrows
+INSERT INTO chain_metadata (chain_id, genesis_hash)
+VALUES ($1, $2)
+ON CONFLICT (id) DO NOTHING;
+
+-- name: GetChainMetadata :one
+SELECT chain_id, genesis_hash, created_at
+FROM chain_metadata;
\ No newline at end of file
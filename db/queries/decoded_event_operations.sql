@@ -0,0 +1,29 @@
+-- name: BatchCreateDecodedEvent :batchexec
+INSERT INTO decoded_events (tx_hash, log_index, block_number, block_hash, tx_index, contract_address, event_name, event_signature, args)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+ON CONFLICT (tx_hash, log_index) DO UPDATE SET
+    block_number = EXCLUDED.block_number,
+    block_hash = EXCLUDED.block_hash,
+    tx_index = EXCLUDED.tx_index,
+    contract_address = EXCLUDED.contract_address,
+    event_name = EXCLUDED.event_name,
+    event_signature = EXCLUDED.event_signature,
+    args = EXCLUDED.args,
+    is_canonical = TRUE,
+    reorg_detected_at = NULL;
+
+-- name: ListDecodedEventsByContract :many
+SELECT tx_hash, log_index, block_number, block_hash, tx_index, contract_address, event_name, event_signature, args
+FROM decoded_events
+WHERE contract_address = $1 AND event_name = $2 AND is_canonical = TRUE
+ORDER BY block_number DESC, log_index DESC
+LIMIT $3 OFFSET $4;
+
+-- name: DeleteDecodedEventsFromHeight :exec
+DELETE FROM decoded_events
+WHERE block_number > $1;
+
+-- name: MarkDecodedEventsReorgedRange :exec
+UPDATE decoded_events
+SET is_canonical = FALSE, reorg_detected_at = NOW()
+WHERE block_number > $1;
\ No newline at end of file
@@ -0,0 +1,26 @@
+-- name: BatchCreateInternalTransfer :batchexec
+INSERT INTO internal_transfers (tx_hash, trace_address, block_number, call_type, from_address, to_address, value)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (tx_hash, trace_address) DO UPDATE SET
+    block_number = EXCLUDED.block_number,
+    call_type = EXCLUDED.call_type,
+    from_address = EXCLUDED.from_address,
+    to_address = EXCLUDED.to_address,
+    value = EXCLUDED.value,
+    is_canonical = TRUE,
+    reorg_detected_at = NULL;
+
+-- name: ListInternalTransfersByTxHash :many
+SELECT tx_hash, trace_address, block_number, call_type, from_address, to_address, value
+FROM internal_transfers
+WHERE tx_hash = $1
+ORDER BY trace_address ASC;
+
+-- name: DeleteInternalTransfersFromHeight :exec
+DELETE FROM internal_transfers
+WHERE block_number > $1;
+
+-- name: MarkInternalTransfersReorgedRange :exec
+UPDATE internal_transfers
+SET is_canonical = FALSE, reorg_detected_at = NOW()
+WHERE block_number > $1;
\ No newline at end of file
@@ -0,0 +1,36 @@
+-- name: BatchCreatePendingTransaction :batchexec
+INSERT INTO pending_transactions (tx_hash, from_address, to_address, nonce, value, gas_fee_cap, input)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (tx_hash) DO NOTHING;
+
+-- name: GetPendingTransaction :one
+SELECT tx_hash, from_address, to_address, nonce, value, gas_fee_cap, input, status, block_number, replaced_by, first_seen_at, updated_at
+FROM pending_transactions
+WHERE tx_hash = $1;
+
+-- name: ListPendingTransactionsByToAddress :many
+SELECT tx_hash, from_address, to_address, nonce, value, gas_fee_cap, input, status, block_number, replaced_by, first_seen_at, updated_at
+FROM pending_transactions
+WHERE to_address = $1 AND status = 'PENDING'
+ORDER BY first_seen_at DESC
+LIMIT $2 OFFSET $3;
+
+-- name: ConfirmPendingTransactions :execrows
+UPDATE pending_transactions
+SET status = 'CONFIRMED', block_number = @block_number, updated_at = NOW()
+WHERE tx_hash = ANY(@tx_hashes::text[]) AND status IN ('PENDING', 'DROPPED');
+
+-- name: BatchReplacePendingTransaction :batchexec
+UPDATE pending_transactions
+SET status = 'REPLACED', replaced_by = $3, block_number = $4, updated_at = NOW()
+WHERE from_address = $1 AND nonce = $2 AND tx_hash != $3 AND status IN ('PENDING', 'DROPPED');
+
+-- name: DropStalePendingTransactions :execrows
+UPDATE pending_transactions
+SET status = 'DROPPED', updated_at = NOW()
+WHERE status = 'PENDING' AND updated_at < NOW() - $1::interval;
+
+-- name: ResetPendingTransactionsFromHeight :exec
+UPDATE pending_transactions
+SET status = 'PENDING', block_number = NULL, replaced_by = NULL, updated_at = NOW()
+WHERE block_number > $1;
\ No newline at end of file
@@ -0,0 +1,36 @@
+-- name: BatchCreateReceipt :batchexec
+INSERT INTO receipts (tx_hash, block_number, block_hash, tx_index, tx_type, status, gas_used, cumulative_gas_used, effective_gas_price, contract_address, logs)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+ON CONFLICT (tx_hash) DO UPDATE SET
+    block_number = EXCLUDED.block_number,
+    block_hash = EXCLUDED.block_hash,
+    tx_index = EXCLUDED.tx_index,
+    status = EXCLUDED.status,
+    gas_used = EXCLUDED.gas_used,
+    cumulative_gas_used = EXCLUDED.cumulative_gas_used,
+    effective_gas_price = EXCLUDED.effective_gas_price,
+    contract_address = EXCLUDED.contract_address,
+    logs = EXCLUDED.logs,
+    is_canonical = TRUE,
+    reorg_detected_at = NULL;
+
+-- name: GetReceipt :one
+SELECT tx_hash, block_number, block_hash, tx_index, tx_type, status, gas_used, cumulative_gas_used, effective_gas_price, contract_address, logs
+FROM receipts
+WHERE tx_hash = $1;
+
+-- name: ListFailedReceipts :many
+SELECT tx_hash, block_number, block_hash, tx_index, tx_type, status, gas_used, cumulative_gas_used, effective_gas_price, contract_address, logs
+FROM receipts
+WHERE status = 0 AND is_canonical = TRUE
+ORDER BY block_number DESC, tx_index ASC
+LIMIT $1 OFFSET $2;
+
+-- name: DeleteReceiptsFromHeight :exec
+DELETE FROM receipts
+WHERE block_number > $1;
+
+-- name: MarkReceiptsReorgedRange :exec
+UPDATE receipts
+SET is_canonical = FALSE, reorg_detected_at = NOW()
+WHERE block_number > $1;
\ No newline at end of file
@@ -8,6 +8,7 @@ package sqlc
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
@@ -17,10 +18,146 @@ var (
 	ErrBatchAlreadyClosed = errors.New("batch already closed")
 )
 
+const batchCreateBlock = `-- name: BatchCreateBlock :batchexec
+INSERT INTO blocks (hash, number, parent_hash, timestamp)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (hash, number) DO UPDATE SET is_canonical = TRUE, reorg_detected_at = NULL
+`
+
+type BatchCreateBlockBatchResults struct {
+	br     pgx.BatchResults
+	tot    int
+	closed bool
+}
+
+type BatchCreateBlockParams struct {
+	Hash       string    `json:"hash"`
+	Number     int64     `json:"number"`
+	ParentHash string    `json:"parentHash"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+func (q *Queries) BatchCreateBlock(ctx context.Context, arg []BatchCreateBlockParams) *BatchCreateBlockBatchResults {
+	batch := &pgx.Batch{}
+	for _, a := range arg {
+		vals := []interface{}{
+			a.Hash,
+			a.Number,
+			a.ParentHash,
+			a.Timestamp,
+		}
+		batch.Queue(batchCreateBlock, vals...)
+	}
+	br := q.db.SendBatch(ctx, batch)
+	return &BatchCreateBlockBatchResults{br, len(arg), false}
+}
+
+func (b *BatchCreateBlockBatchResults) Exec(f func(int, error)) {
+	defer b.br.Close()
+	for t := 0; t < b.tot; t++ {
+		if b.closed {
+			if f != nil {
+				f(t, ErrBatchAlreadyClosed)
+			}
+			continue
+		}
+		_, err := b.br.Exec()
+		if f != nil {
+			f(t, err)
+		}
+	}
+}
+
+func (b *BatchCreateBlockBatchResults) Close() error {
+	b.closed = true
+	return b.br.Close()
+}
+
+const batchCreateDecodedEvent = `-- name: BatchCreateDecodedEvent :batchexec
+INSERT INTO decoded_events (tx_hash, log_index, block_number, block_hash, tx_index, contract_address, event_name, event_signature, args)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+ON CONFLICT (tx_hash, log_index) DO UPDATE SET
+    block_number = EXCLUDED.block_number,
+    block_hash = EXCLUDED.block_hash,
+    tx_index = EXCLUDED.tx_index,
+    contract_address = EXCLUDED.contract_address,
+    event_name = EXCLUDED.event_name,
+    event_signature = EXCLUDED.event_signature,
+    args = EXCLUDED.args,
+    is_canonical = TRUE,
+    reorg_detected_at = NULL
+`
+
+type BatchCreateDecodedEventBatchResults struct {
+	br     pgx.BatchResults
+	tot    int
+	closed bool
+}
+
+type BatchCreateDecodedEventParams struct {
+	TxHash          string `json:"txHash"`
+	LogIndex        int32  `json:"logIndex"`
+	BlockNumber     int64  `json:"blockNumber"`
+	BlockHash       string `json:"blockHash"`
+	TxIndex         int32  `json:"txIndex"`
+	ContractAddress string `json:"contractAddress"`
+	EventName       string `json:"eventName"`
+	EventSignature  string `json:"eventSignature"`
+	Args            []byte `json:"args"`
+}
+
+func (q *Queries) BatchCreateDecodedEvent(ctx context.Context, arg []BatchCreateDecodedEventParams) *BatchCreateDecodedEventBatchResults {
+	batch := &pgx.Batch{}
+	for _, a := range arg {
+		vals := []interface{}{
+			a.TxHash,
+			a.LogIndex,
+			a.BlockNumber,
+			a.BlockHash,
+			a.TxIndex,
+			a.ContractAddress,
+			a.EventName,
+			a.EventSignature,
+			a.Args,
+		}
+		batch.Queue(batchCreateDecodedEvent, vals...)
+	}
+	br := q.db.SendBatch(ctx, batch)
+	return &BatchCreateDecodedEventBatchResults{br, len(arg), false}
+}
+
+func (b *BatchCreateDecodedEventBatchResults) Exec(f func(int, error)) {
+	defer b.br.Close()
+	for t := 0; t < b.tot; t++ {
+		if b.closed {
+			if f != nil {
+				f(t, ErrBatchAlreadyClosed)
+			}
+			continue
+		}
+		_, err := b.br.Exec()
+		if f != nil {
+			f(t, err)
+		}
+	}
+}
+
+func (b *BatchCreateDecodedEventBatchResults) Close() error {
+	b.closed = true
+	return b.br.Close()
+}
+
 const batchCreateERC20Transfer = `-- name: BatchCreateERC20Transfer :batchexec
 INSERT INTO erc20_transfers (tx_hash, log_index, from_address, to_address, value, block_number, token_address)
 VALUES ($1, $2, $3, $4, $5, $6, $7)
-ON CONFLICT (tx_hash, log_index) DO UPDATE SET is_canonical = TRUE, reorg_detected_at = NULL
+ON CONFLICT (tx_hash, log_index) DO UPDATE SET
+    from_address = EXCLUDED.from_address,
+    to_address = EXCLUDED.to_address,
+    value = EXCLUDED.value,
+    block_number = EXCLUDED.block_number,
+    token_address = EXCLUDED.token_address,
+    is_canonical = TRUE,
+    reorg_detected_at = NULL
 `
 
 type BatchCreateERC20TransferBatchResults struct {
@@ -77,3 +214,267 @@ func (b *BatchCreateERC20TransferBatchResults) Close() error {
 	b.closed = true
 	return b.br.Close()
 }
+
+const batchCreateInternalTransfer = `-- name: BatchCreateInternalTransfer :batchexec
+INSERT INTO internal_transfers (tx_hash, trace_address, block_number, call_type, from_address, to_address, value)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (tx_hash, trace_address) DO UPDATE SET
+    block_number = EXCLUDED.block_number,
+    call_type = EXCLUDED.call_type,
+    from_address = EXCLUDED.from_address,
+    to_address = EXCLUDED.to_address,
+    value = EXCLUDED.value,
+    is_canonical = TRUE,
+    reorg_detected_at = NULL
+`
+
+type BatchCreateInternalTransferBatchResults struct {
+	br     pgx.BatchResults
+	tot    int
+	closed bool
+}
+
+type BatchCreateInternalTransferParams struct {
+	TxHash       string         `json:"txHash"`
+	TraceAddress string         `json:"traceAddress"`
+	BlockNumber  int64          `json:"blockNumber"`
+	CallType     string         `json:"callType"`
+	FromAddress  string         `json:"fromAddress"`
+	ToAddress    string         `json:"toAddress"`
+	Value        pgtype.Numeric `json:"value"`
+}
+
+func (q *Queries) BatchCreateInternalTransfer(ctx context.Context, arg []BatchCreateInternalTransferParams) *BatchCreateInternalTransferBatchResults {
+	batch := &pgx.Batch{}
+	for _, a := range arg {
+		vals := []interface{}{
+			a.TxHash,
+			a.TraceAddress,
+			a.BlockNumber,
+			a.CallType,
+			a.FromAddress,
+			a.ToAddress,
+			a.Value,
+		}
+		batch.Queue(batchCreateInternalTransfer, vals...)
+	}
+	br := q.db.SendBatch(ctx, batch)
+	return &BatchCreateInternalTransferBatchResults{br, len(arg), false}
+}
+
+func (b *BatchCreateInternalTransferBatchResults) Exec(f func(int, error)) {
+	defer b.br.Close()
+	for t := 0; t < b.tot; t++ {
+		if b.closed {
+			if f != nil {
+				f(t, ErrBatchAlreadyClosed)
+			}
+			continue
+		}
+		_, err := b.br.Exec()
+		if f != nil {
+			f(t, err)
+		}
+	}
+}
+
+func (b *BatchCreateInternalTransferBatchResults) Close() error {
+	b.closed = true
+	return b.br.Close()
+}
+
+const batchCreatePendingTransaction = `-- name: BatchCreatePendingTransaction :batchexec
+INSERT INTO pending_transactions (tx_hash, from_address, to_address, nonce, value, gas_fee_cap, input)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (tx_hash) DO NOTHING
+`
+
+type BatchCreatePendingTransactionBatchResults struct {
+	br     pgx.BatchResults
+	tot    int
+	closed bool
+}
+
+type BatchCreatePendingTransactionParams struct {
+	TxHash      string         `json:"txHash"`
+	FromAddress string         `json:"fromAddress"`
+	ToAddress   pgtype.Text    `json:"toAddress"`
+	Nonce       int64          `json:"nonce"`
+	Value       pgtype.Numeric `json:"value"`
+	GasFeeCap   pgtype.Numeric `json:"gasFeeCap"`
+	Input       []byte         `json:"input"`
+}
+
+func (q *Queries) BatchCreatePendingTransaction(ctx context.Context, arg []BatchCreatePendingTransactionParams) *BatchCreatePendingTransactionBatchResults {
+	batch := &pgx.Batch{}
+	for _, a := range arg {
+		vals := []interface{}{
+			a.TxHash,
+			a.FromAddress,
+			a.ToAddress,
+			a.Nonce,
+			a.Value,
+			a.GasFeeCap,
+			a.Input,
+		}
+		batch.Queue(batchCreatePendingTransaction, vals...)
+	}
+	br := q.db.SendBatch(ctx, batch)
+	return &BatchCreatePendingTransactionBatchResults{br, len(arg), false}
+}
+
+func (b *BatchCreatePendingTransactionBatchResults) Exec(f func(int, error)) {
+	defer b.br.Close()
+	for t := 0; t < b.tot; t++ {
+		if b.closed {
+			if f != nil {
+				f(t, ErrBatchAlreadyClosed)
+			}
+			continue
+		}
+		_, err := b.br.Exec()
+		if f != nil {
+			f(t, err)
+		}
+	}
+}
+
+func (b *BatchCreatePendingTransactionBatchResults) Close() error {
+	b.closed = true
+	return b.br.Close()
+}
+
+const batchCreateReceipt = `-- name: BatchCreateReceipt :batchexec
+INSERT INTO receipts (tx_hash, block_number, block_hash, tx_index, tx_type, status, gas_used, cumulative_gas_used, effective_gas_price, contract_address, logs)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+ON CONFLICT (tx_hash) DO UPDATE SET
+    block_number = EXCLUDED.block_number,
+    block_hash = EXCLUDED.block_hash,
+    tx_index = EXCLUDED.tx_index,
+    status = EXCLUDED.status,
+    gas_used = EXCLUDED.gas_used,
+    cumulative_gas_used = EXCLUDED.cumulative_gas_used,
+    effective_gas_price = EXCLUDED.effective_gas_price,
+    contract_address = EXCLUDED.contract_address,
+    logs = EXCLUDED.logs,
+    is_canonical = TRUE,
+    reorg_detected_at = NULL
+`
+
+type BatchCreateReceiptBatchResults struct {
+	br     pgx.BatchResults
+	tot    int
+	closed bool
+}
+
+type BatchCreateReceiptParams struct {
+	TxHash            string         `json:"txHash"`
+	BlockNumber       int64          `json:"blockNumber"`
+	BlockHash         string         `json:"blockHash"`
+	TxIndex           int32          `json:"txIndex"`
+	TxType            int16          `json:"txType"`
+	Status            int16          `json:"status"`
+	GasUsed           int64          `json:"gasUsed"`
+	CumulativeGasUsed int64          `json:"cumulativeGasUsed"`
+	EffectiveGasPrice pgtype.Numeric `json:"effectiveGasPrice"`
+	ContractAddress   pgtype.Text    `json:"contractAddress"`
+	Logs              []byte         `json:"logs"`
+}
+
+func (q *Queries) BatchCreateReceipt(ctx context.Context, arg []BatchCreateReceiptParams) *BatchCreateReceiptBatchResults {
+	batch := &pgx.Batch{}
+	for _, a := range arg {
+		vals := []interface{}{
+			a.TxHash,
+			a.BlockNumber,
+			a.BlockHash,
+			a.TxIndex,
+			a.TxType,
+			a.Status,
+			a.GasUsed,
+			a.CumulativeGasUsed,
+			a.EffectiveGasPrice,
+			a.ContractAddress,
+			a.Logs,
+		}
+		batch.Queue(batchCreateReceipt, vals...)
+	}
+	br := q.db.SendBatch(ctx, batch)
+	return &BatchCreateReceiptBatchResults{br, len(arg), false}
+}
+
+func (b *BatchCreateReceiptBatchResults) Exec(f func(int, error)) {
+	defer b.br.Close()
+	for t := 0; t < b.tot; t++ {
+		if b.closed {
+			if f != nil {
+				f(t, ErrBatchAlreadyClosed)
+			}
+			continue
+		}
+		_, err := b.br.Exec()
+		if f != nil {
+			f(t, err)
+		}
+	}
+}
+
+func (b *BatchCreateReceiptBatchResults) Close() error {
+	b.closed = true
+	return b.br.Close()
+}
+
+const batchReplacePendingTransaction = `-- name: BatchReplacePendingTransaction :batchexec
+UPDATE pending_transactions
+SET status = 'REPLACED', replaced_by = $3, block_number = $4, updated_at = NOW()
+WHERE from_address = $1 AND nonce = $2 AND tx_hash != $3 AND status IN ('PENDING', 'DROPPED')
+`
+
+type BatchReplacePendingTransactionBatchResults struct {
+	br     pgx.BatchResults
+	tot    int
+	closed bool
+}
+
+type BatchReplacePendingTransactionParams struct {
+	FromAddress string      `json:"fromAddress"`
+	Nonce       int64       `json:"nonce"`
+	ReplacedBy  pgtype.Text `json:"replacedBy"`
+	BlockNumber pgtype.Int8 `json:"blockNumber"`
+}
+
+func (q *Queries) BatchReplacePendingTransaction(ctx context.Context, arg []BatchReplacePendingTransactionParams) *BatchReplacePendingTransactionBatchResults {
+	batch := &pgx.Batch{}
+	for _, a := range arg {
+		vals := []interface{}{
+			a.FromAddress,
+			a.Nonce,
+			a.ReplacedBy,
+			a.BlockNumber,
+		}
+		batch.Queue(batchReplacePendingTransaction, vals...)
+	}
+	br := q.db.SendBatch(ctx, batch)
+	return &BatchReplacePendingTransactionBatchResults{br, len(arg), false}
+}
+
+func (b *BatchReplacePendingTransactionBatchResults) Exec(f func(int, error)) {
+	defer b.br.Close()
+	for t := 0; t < b.tot; t++ {
+		if b.closed {
+			if f != nil {
+				f(t, ErrBatchAlreadyClosed)
+			}
+			continue
+		}
+		_, err := b.br.Exec()
+		if f != nil {
+			f(t, err)
+		}
+	}
+}
+
+func (b *BatchReplacePendingTransactionBatchResults) Close() error {
+	b.closed = true
+	return b.br.Close()
+}
@@ -195,6 +195,19 @@ func (q *Queries) GetLatestProcessedBlockNumber(ctx context.Context) (int64, err
 	return column_1, err
 }
 
+const hasCanonicalBlockBelow = `-- name: HasCanonicalBlockBelow :one
+SELECT EXISTS (
+    SELECT 1 FROM blocks WHERE number < $1 AND is_canonical = TRUE
+)
+`
+
+func (q *Queries) HasCanonicalBlockBelow(ctx context.Context, number int64) (bool, error) {
+	row := q.db.QueryRow(ctx, hasCanonicalBlockBelow, number)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
 const listBlocks = `-- name: ListBlocks :many
 SELECT id, hash, number, parent_hash, timestamp
 FROM blocks
@@ -242,6 +255,20 @@ func (q *Queries) ListBlocks(ctx context.Context, arg ListBlocksParams) ([]ListB
 	return items, nil
 }
 
+const lockCanonicalBlock = `-- name: LockCanonicalBlock :one
+SELECT hash
+FROM blocks
+WHERE number = $1 AND is_canonical = TRUE
+FOR UPDATE
+`
+
+func (q *Queries) LockCanonicalBlock(ctx context.Context, number int64) (string, error) {
+	row := q.db.QueryRow(ctx, lockCanonicalBlock, number)
+	var hash string
+	err := row.Scan(&hash)
+	return hash, err
+}
+
 const markBlockFinalized = `-- name: MarkBlockFinalized :exec
 UPDATE blocks
 SET status = 'FINALIZED'
@@ -264,9 +291,25 @@ func (q *Queries) MarkBlockProcessed(ctx context.Context, number int64) error {
 	return err
 }
 
+const markBlockProcessedRange = `-- name: MarkBlockProcessedRange :exec
+UPDATE blocks
+SET processed_at = NOW()
+WHERE number >= $1 AND number <= $2 AND processed_at IS NULL
+`
+
+type MarkBlockProcessedRangeParams struct {
+	FromBlock int64 `json:"fromBlock"`
+	ToBlock   int64 `json:"toBlock"`
+}
+
+func (q *Queries) MarkBlockProcessedRange(ctx context.Context, arg MarkBlockProcessedRangeParams) error {
+	_, err := q.db.Exec(ctx, markBlockProcessedRange, arg.FromBlock, arg.ToBlock)
+	return err
+}
+
 const markBlockReorgedRange = `-- name: MarkBlockReorgedRange :exec
 UPDATE blocks
-SET is_canonical = FALSE, reorg_detected_at = NOW()
+SET is_canonical = FALSE, reorg_detected_at = NOW(), status = 'PENDING'
 WHERE number > $1
 `
 
@@ -275,6 +318,17 @@ func (q *Queries) MarkBlockReorgedRange(ctx context.Context, number int64) error
 	return err
 }
 
+const markBlockSafe = `-- name: MarkBlockSafe :exec
+UPDATE blocks
+SET status = 'SAFE'
+WHERE number <= $1 AND is_canonical = TRUE AND status = 'PENDING'
+`
+
+func (q *Queries) MarkBlockSafe(ctx context.Context, number int64) error {
+	_, err := q.db.Exec(ctx, markBlockSafe, number)
+	return err
+}
+
 const updateBlock = `-- name: UpdateBlock :one
 UPDATE blocks
 SET hash = $2, number = $3, parent_hash = $4, timestamp = $5
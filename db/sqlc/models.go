@@ -23,6 +23,37 @@ type Block struct {
 	Status          pgtype.Text      `json:"status"`
 }
 
+type BlockDiscrepancy struct {
+	ID            int32            `json:"id"`
+	BlockNumber   int64            `json:"blockNumber"`
+	Kind          string           `json:"kind"`
+	Expected      string           `json:"expected"`
+	Observed      []byte           `json:"observed"`
+	QuorumReached bool             `json:"quorumReached"`
+	CreatedAt     pgtype.Timestamp `json:"createdAt"`
+}
+
+type ChainMetadatum struct {
+	ID          bool             `json:"id"`
+	ChainID     int64            `json:"chainId"`
+	GenesisHash string           `json:"genesisHash"`
+	CreatedAt   pgtype.Timestamp `json:"createdAt"`
+}
+
+type DecodedEvent struct {
+	TxHash          string           `json:"txHash"`
+	LogIndex        int32            `json:"logIndex"`
+	BlockNumber     int64            `json:"blockNumber"`
+	BlockHash       string           `json:"blockHash"`
+	TxIndex         int32            `json:"txIndex"`
+	ContractAddress string           `json:"contractAddress"`
+	EventName       string           `json:"eventName"`
+	EventSignature  string           `json:"eventSignature"`
+	Args            []byte           `json:"args"`
+	IsCanonical     pgtype.Bool      `json:"isCanonical"`
+	ReorgDetectedAt pgtype.Timestamp `json:"reorgDetectedAt"`
+}
+
 type Erc20Transfer struct {
 	TxHash          string           `json:"txHash"`
 	LogIndex        int32            `json:"logIndex"`
@@ -34,3 +65,46 @@ type Erc20Transfer struct {
 	ReorgDetectedAt pgtype.Timestamp `json:"reorgDetectedAt"`
 	TokenAddress    string           `json:"tokenAddress"`
 }
+
+type InternalTransfer struct {
+	TxHash          string           `json:"txHash"`
+	TraceAddress    string           `json:"traceAddress"`
+	BlockNumber     int64            `json:"blockNumber"`
+	CallType        string           `json:"callType"`
+	FromAddress     string           `json:"fromAddress"`
+	ToAddress       string           `json:"toAddress"`
+	Value           pgtype.Numeric   `json:"value"`
+	IsCanonical     pgtype.Bool      `json:"isCanonical"`
+	ReorgDetectedAt pgtype.Timestamp `json:"reorgDetectedAt"`
+}
+
+type PendingTransaction struct {
+	TxHash      string         `json:"txHash"`
+	FromAddress string         `json:"fromAddress"`
+	ToAddress   pgtype.Text    `json:"toAddress"`
+	Nonce       int64          `json:"nonce"`
+	Value       pgtype.Numeric `json:"value"`
+	GasFeeCap   pgtype.Numeric `json:"gasFeeCap"`
+	Input       []byte         `json:"input"`
+	Status      string         `json:"status"`
+	BlockNumber pgtype.Int8    `json:"blockNumber"`
+	ReplacedBy  pgtype.Text    `json:"replacedBy"`
+	FirstSeenAt time.Time      `json:"firstSeenAt"`
+	UpdatedAt   time.Time      `json:"updatedAt"`
+}
+
+type Receipt struct {
+	TxHash            string           `json:"txHash"`
+	BlockNumber       int64            `json:"blockNumber"`
+	BlockHash         string           `json:"blockHash"`
+	TxIndex           int32            `json:"txIndex"`
+	TxType            int16            `json:"txType"`
+	Status            int16            `json:"status"`
+	GasUsed           int64            `json:"gasUsed"`
+	CumulativeGasUsed int64            `json:"cumulativeGasUsed"`
+	EffectiveGasPrice pgtype.Numeric   `json:"effectiveGasPrice"`
+	ContractAddress   pgtype.Text      `json:"contractAddress"`
+	Logs              []byte           `json:"logs"`
+	IsCanonical       pgtype.Bool      `json:"isCanonical"`
+	ReorgDetectedAt   pgtype.Timestamp `json:"reorgDetectedAt"`
+}
@@ -6,30 +6,61 @@ package sqlc
 
 import (
 	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type Querier interface {
+	BatchCreateBlock(ctx context.Context, arg []BatchCreateBlockParams) *BatchCreateBlockBatchResults
+	BatchCreateDecodedEvent(ctx context.Context, arg []BatchCreateDecodedEventParams) *BatchCreateDecodedEventBatchResults
 	BatchCreateERC20Transfer(ctx context.Context, arg []BatchCreateERC20TransferParams) *BatchCreateERC20TransferBatchResults
+	BatchCreateInternalTransfer(ctx context.Context, arg []BatchCreateInternalTransferParams) *BatchCreateInternalTransferBatchResults
+	BatchCreatePendingTransaction(ctx context.Context, arg []BatchCreatePendingTransactionParams) *BatchCreatePendingTransactionBatchResults
+	BatchCreateReceipt(ctx context.Context, arg []BatchCreateReceiptParams) *BatchCreateReceiptBatchResults
+	BatchReplacePendingTransaction(ctx context.Context, arg []BatchReplacePendingTransactionParams) *BatchReplacePendingTransactionBatchResults
+	ConfirmPendingTransactions(ctx context.Context, arg ConfirmPendingTransactionsParams) (int64, error)
 	CountBlocks(ctx context.Context) (int64, error)
 	CountERC20Transfers(ctx context.Context) (int64, error)
 	CreateBlock(ctx context.Context, arg CreateBlockParams) (CreateBlockRow, error)
+	CreateBlockDiscrepancy(ctx context.Context, arg CreateBlockDiscrepancyParams) error
+	CreateChainMetadata(ctx context.Context, arg CreateChainMetadataParams) (int64, error)
 	CreateERC20Transfer(ctx context.Context, arg CreateERC20TransferParams) (CreateERC20TransferRow, error)
 	DeleteBlock(ctx context.Context, id int32) error
 	DeleteBlockByHash(ctx context.Context, hash string) error
 	DeleteBlocksFromHeight(ctx context.Context, number int64) error
+	DeleteDecodedEventsFromHeight(ctx context.Context, blockNumber int64) error
 	DeleteERC20TransfersFromHeight(ctx context.Context, blockNumber int64) error
+	DeleteInternalTransfersFromHeight(ctx context.Context, blockNumber int64) error
+	DeleteReceiptsFromHeight(ctx context.Context, blockNumber int64) error
+	DropStalePendingTransactions(ctx context.Context, dollar_1 pgtype.Interval) (int64, error)
 	GetBlockByHash(ctx context.Context, hash string) (GetBlockByHashRow, error)
 	GetBlockByID(ctx context.Context, id int32) (GetBlockByIDRow, error)
 	GetBlockByNumber(ctx context.Context, number int64) (GetBlockByNumberRow, error)
+	GetChainMetadata(ctx context.Context) (GetChainMetadataRow, error)
 	GetERC20Transfer(ctx context.Context, arg GetERC20TransferParams) (GetERC20TransferRow, error)
 	GetLatestBlockNumber(ctx context.Context) (int64, error)
 	GetLatestProcessedBlockNumber(ctx context.Context) (int64, error)
+	GetPendingTransaction(ctx context.Context, txHash string) (PendingTransaction, error)
+	GetReceipt(ctx context.Context, txHash string) (GetReceiptRow, error)
+	HasCanonicalBlockBelow(ctx context.Context, number int64) (bool, error)
+	ListBlockDiscrepancies(ctx context.Context, arg ListBlockDiscrepanciesParams) ([]BlockDiscrepancy, error)
 	ListBlocks(ctx context.Context, arg ListBlocksParams) ([]ListBlocksRow, error)
+	ListDecodedEventsByContract(ctx context.Context, arg ListDecodedEventsByContractParams) ([]ListDecodedEventsByContractRow, error)
 	ListERC20TransfersByTxHash(ctx context.Context, arg ListERC20TransfersByTxHashParams) ([]ListERC20TransfersByTxHashRow, error)
+	ListFailedReceipts(ctx context.Context, arg ListFailedReceiptsParams) ([]ListFailedReceiptsRow, error)
+	ListInternalTransfersByTxHash(ctx context.Context, txHash string) ([]ListInternalTransfersByTxHashRow, error)
+	ListPendingTransactionsByToAddress(ctx context.Context, arg ListPendingTransactionsByToAddressParams) ([]PendingTransaction, error)
+	LockCanonicalBlock(ctx context.Context, number int64) (string, error)
 	MarkBlockFinalized(ctx context.Context, number int64) error
 	MarkBlockProcessed(ctx context.Context, number int64) error
+	MarkBlockProcessedRange(ctx context.Context, arg MarkBlockProcessedRangeParams) error
 	MarkBlockReorgedRange(ctx context.Context, number int64) error
+	MarkBlockSafe(ctx context.Context, number int64) error
+	MarkDecodedEventsReorgedRange(ctx context.Context, blockNumber int64) error
 	MarkERC20TransfersReorgedRange(ctx context.Context, blockNumber int64) error
+	MarkInternalTransfersReorgedRange(ctx context.Context, blockNumber int64) error
+	MarkReceiptsReorgedRange(ctx context.Context, blockNumber int64) error
+	ResetPendingTransactionsFromHeight(ctx context.Context, blockNumber pgtype.Int8) error
 	UpdateBlock(ctx context.Context, arg UpdateBlockParams) (UpdateBlockRow, error)
 }
 
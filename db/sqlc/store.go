@@ -44,6 +44,15 @@ func NewStore() (Store, error) {
 	}, nil
 }
 
+// NewStoreFromPool creates a store on an existing connection pool, for
+// connections configured other than through the environment.
+func NewStoreFromPool(connPool *pgxpool.Pool) Store {
+	return &SQLStore{
+		connPool: connPool,
+		Queries:  New(connPool),
+	}
+}
+
 func (s SQLStore) Close() {
 	s.connPool.Close()
 }
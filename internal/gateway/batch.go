@@ -0,0 +1,229 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// DefaultBatchSize is the number of blocks fetched per JSON-RPC batch request.
+// Each batch of blocks takes two requests, one for the headers and one for
+// the logs pinned to those headers.
+const DefaultBatchSize = 50
+
+// BlockLogs is a block header together with the logs it emitted that match
+// the fetcher's log filters, ERC20 Transfer logs by default.
+type BlockLogs struct {
+	Header *types.Header
+	Logs   []types.Log
+}
+
+var (
+	// errHeaderNotFound is set on a batch element when the node returned null for a
+	// block header, usually because it has not seen that block yet (node lag).
+	errHeaderNotFound = errors.New("header not found")
+	// ErrUnknownBlockHash is returned when the node does not know the block hash
+	// logs were pinned to, usually because the block was reorged out after its
+	// header was fetched. Refetching the block picks up the new canonical one.
+	ErrUnknownBlockHash = errors.New("block hash unknown to the node")
+)
+
+// GetBlocksInRange fetches headers and filtered logs for every block from
+// startBlock to endBlock using JSON-RPC batch requests of at most the configured
+// batch size. Blocks are returned in ascending order. When an attempt fails
+// part way, the next attempt of the same call resumes where it stopped and
+// only re-sends the batch elements that failed.
+func (bf *blockFetcher) GetBlocksInRange(ctx context.Context, startBlock, endBlock uint64) ([]BlockLogs, error) {
+	if endBlock < startBlock {
+		return nil, fmt.Errorf("invalid block range %d-%d", startBlock, endBlock)
+	}
+	progress := scopeValue(ctx, scopeKey{bf: bf, name: "blocks", from: startBlock, to: endBlock}, func() *blockRange {
+		return &blockRange{blocks: make([]BlockLogs, 0, endBlock-startBlock+1), next: startBlock}
+	})
+	for progress.next <= endBlock {
+		from := progress.next
+		to := min(from+uint64(bf.batchSize)-1, endBlock)
+		batch, err := bf.getBlockBatch(ctx, from, to)
+		if err != nil {
+			return nil, err
+		}
+		progress.blocks = append(progress.blocks, batch...)
+		progress.next = to + 1
+	}
+	return progress.blocks, nil
+}
+
+// blockRange is what earlier attempts of a GetBlocksInRange call fetched.
+type blockRange struct {
+	blocks []BlockLogs
+	next   uint64
+}
+
+// GetHeadersInRange fetches the headers of every block from startBlock to
+// endBlock using JSON-RPC batch requests of at most the configured batch
+// size, without their logs. Headers are returned in ascending order, and
+// retried attempts resume where the previous one stopped.
+func (bf *blockFetcher) GetHeadersInRange(ctx context.Context, startBlock, endBlock uint64) ([]*types.Header, error) {
+	if endBlock < startBlock {
+		return nil, fmt.Errorf("invalid block range %d-%d", startBlock, endBlock)
+	}
+	progress := scopeValue(ctx, scopeKey{bf: bf, name: "headers", from: startBlock, to: endBlock}, func() *headerRange {
+		return &headerRange{headers: make([]*types.Header, 0, endBlock-startBlock+1), next: startBlock}
+	})
+	for progress.next <= endBlock {
+		from := progress.next
+		to := min(from+uint64(bf.batchSize)-1, endBlock)
+		b := scopeValue(ctx, scopeKey{bf: bf, name: "headerBatch", from: from, to: to}, func() *blockBatch {
+			return newHeaderBatch(from, to)
+		})
+		if err := bf.callBatch(ctx, b); err != nil {
+			return nil, err
+		}
+		if err := checkHeaderNumbers(from, b.headers); err != nil {
+			return nil, err
+		}
+		progress.headers = append(progress.headers, b.headers...)
+		progress.next = to + 1
+	}
+	return progress.headers, nil
+}
+
+// headerRange is what earlier attempts of a GetHeadersInRange call fetched.
+type headerRange struct {
+	headers []*types.Header
+	next    uint64
+}
+
+// blockBatch is the state of one batch across attempts: the results so far
+// and the elements still to send.
+type blockBatch struct {
+	headers []*types.Header
+	logs    [][]types.Log // per block and filter, nil until all headers are in
+	pending []rpc.BatchElem
+}
+
+// getBlockBatch fetches one batch worth of blocks: first the headers, then
+// the logs of each block by its hash (EIP-234) with every filter, so the logs
+// always belong to the exact header returned even if a reorg happens in
+// between.
+func (bf *blockFetcher) getBlockBatch(ctx context.Context, from, to uint64) ([]BlockLogs, error) {
+	n := int(to - from + 1)
+	b := scopeValue(ctx, scopeKey{bf: bf, name: "batch", from: from, to: to}, func() *blockBatch {
+		return newHeaderBatch(from, to)
+	})
+
+	if b.logs == nil {
+		if err := bf.callBatch(ctx, b); err != nil {
+			return nil, err
+		}
+		if err := checkHeaderNumbers(from, b.headers); err != nil {
+			return nil, err
+		}
+		k := len(bf.logFilters)
+		b.logs = make([][]types.Log, n*k)
+		b.pending = make([]rpc.BatchElem, n*k)
+		for i := range n {
+			for j, filter := range bf.logFilters {
+				query := filter.query()
+				query["blockHash"] = b.headers[i].Hash()
+				b.pending[i*k+j] = rpc.BatchElem{
+					Method: "eth_getLogs",
+					Args:   []any{query},
+					Result: &b.logs[i*k+j],
+				}
+			}
+		}
+	}
+	if err := bf.callBatch(ctx, b); err != nil {
+		return nil, err
+	}
+
+	blocks := make([]BlockLogs, n)
+	k := len(bf.logFilters)
+	for i := range n {
+		blocks[i] = BlockLogs{Header: b.headers[i], Logs: mergeLogs(b.logs[i*k : (i+1)*k]...)}
+	}
+	return blocks, nil
+}
+
+// newHeaderBatch returns a batch fetching the headers of blocks from to to.
+func newHeaderBatch(from, to uint64) *blockBatch {
+	n := int(to - from + 1)
+	b := &blockBatch{headers: make([]*types.Header, n), pending: make([]rpc.BatchElem, n)}
+	for i := range n {
+		b.pending[i] = rpc.BatchElem{
+			Method: "eth_getBlockByNumber",
+			Args:   []any{hexutil.EncodeUint64(from + uint64(i)), false},
+			Result: &b.headers[i],
+		}
+	}
+	return b
+}
+
+// checkHeaderNumbers checks that a batch starting at from returned consecutive headers.
+func checkHeaderNumbers(from uint64, headers []*types.Header) error {
+	for i, h := range headers {
+		if want := from + uint64(i); h.Number.Uint64() != want {
+			return fmt.Errorf("batch returned block %d, expected %d", h.Number.Uint64(), want)
+		}
+	}
+	return nil
+}
+
+// callBatch sends the pending elements of b once. Elements that fail with a
+// retryable error stay pending for the next attempt.
+func (bf *blockFetcher) callBatch(ctx context.Context, b *blockBatch) error {
+	if len(b.pending) == 0 {
+		return nil
+	}
+	tried := bf.tried(ctx)
+	_, err := callEndpoint(ctx, bf.pool, tried, func(ctx context.Context, ep *endpointState) (bool, error) {
+		methods := make([]string, len(b.pending))
+		for i, elem := range b.pending {
+			methods[i] = elem.Method
+		}
+		if err := ep.wait(ctx, methods...); err != nil {
+			return false, err
+		}
+		return true, ep.Client.Client().BatchCallContext(ctx, b.pending)
+	})
+	if err != nil {
+		return err
+	}
+
+	var failed []rpc.BatchElem
+	var elemErr error
+	for _, elem := range b.pending {
+		if elem.Error == nil {
+			if h, ok := elem.Result.(**types.Header); ok && *h == nil {
+				elem.Error = errHeaderNotFound
+			}
+		}
+		if elem.Error == nil {
+			continue
+		}
+		class := ClassifyError(elem.Error)
+		if !class.Retryable() {
+			return fmt.Errorf("%s: %w", elem.Method, elem.Error)
+		}
+		elemErr = elem.Error
+		if class == ClassNodeLag && elem.Method == "eth_getLogs" {
+			// Either this node lags behind the one that served the header, or
+			// the block is gone. Retrying tells the two apart.
+			elemErr = fmt.Errorf("%w: %w", ErrUnknownBlockHash, elem.Error)
+		}
+		elem.Error = nil
+		failed = append(failed, elem)
+	}
+	b.pending = failed
+	if len(failed) > 0 {
+		return fmt.Errorf("%d batch elements failed: %w", len(failed), elemErr)
+	}
+	// The next request of the call starts from the healthiest endpoint again.
+	tried.reset()
+	return nil
+}
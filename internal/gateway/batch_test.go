@@ -0,0 +1,100 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestGetBlocksInRange(t *testing.T) {
+	node := newTestNode(10)
+	fetcher := NewBlockFetcher(node.dial(t), WithBatchSize(4))
+
+	blocks, err := fetcher.GetBlocksInRange(context.Background(), 1, 9)
+	if err != nil {
+		t.Fatalf("Failed to get blocks in range: %v", err)
+	}
+	if len(blocks) != 9 {
+		t.Fatalf("expected 9 blocks, got %d", len(blocks))
+	}
+	for i, b := range blocks {
+		if want := uint64(i + 1); b.Header.Number.Uint64() != want {
+			t.Errorf("block %d: expected number %d, got %d", i, want, b.Header.Number.Uint64())
+		}
+		if b.Header.Hash() != node.headers[b.Header.Number.Uint64()].Hash() {
+			t.Errorf("block %d: header hash mismatch", i)
+		}
+		if len(b.Logs) != 1 {
+			t.Errorf("block %d: expected 1 log, got %d", i, len(b.Logs))
+		}
+	}
+	// 9 blocks in batches of 4 means 3 batches of one header call per block.
+	if got := node.callCount("eth_getBlockByNumber"); got != 9 {
+		t.Errorf("expected 9 header calls, got %d", got)
+	}
+}
+
+func TestGetBlocksInRangeRetriesOnlyFailedElements(t *testing.T) {
+	node := newTestNode(5)
+	node.failLogs[2] = 1
+	fetcher := NewBlockFetcher(node.dial(t))
+
+	blocks, err := fetcher.GetBlocksInRange(context.Background(), 1, 4)
+	if err != nil {
+		t.Fatalf("Failed to get blocks in range: %v", err)
+	}
+	if len(blocks) != 4 || len(blocks[1].Logs) != 1 {
+		t.Fatalf("expected 4 blocks with logs for block 2, got %+v", blocks)
+	}
+	if got := node.callCount("eth_getBlockByNumber"); got != 4 {
+		t.Errorf("expected headers to be fetched once, got %d calls", got)
+	}
+	if got := node.callCount("eth_getLogs"); got != 5 {
+		t.Errorf("expected only the failed logs call to be retried, got %d calls", got)
+	}
+}
+
+func TestGetBlocksInRangePinsLogsToHeaderHash(t *testing.T) {
+	node := newTestNode(5)
+	sibling := types.CopyHeader(node.headers[3])
+	sibling.Time++
+	node.reorgs[3] = sibling // block 3 is replaced right after its header is served
+	fetcher := NewBlockFetcher(node.dial(t))
+
+	_, err := fetcher.GetBlocksInRange(context.Background(), 1, 4)
+	if !errors.Is(err, ErrUnknownBlockHash) {
+		t.Fatalf("expected ErrUnknownBlockHash, got %v", err)
+	}
+
+	// Refetching picks up the new canonical block.
+	blocks, err := fetcher.GetBlocksInRange(context.Background(), 1, 4)
+	if err != nil {
+		t.Fatalf("Failed to refetch blocks: %v", err)
+	}
+	if blocks[2].Header.Hash() != sibling.Hash() {
+		t.Errorf("expected the sibling of block 3, got %s", blocks[2].Header.Hash())
+	}
+}
+
+func TestGetHeadersInRange(t *testing.T) {
+	node := newTestNode(10)
+	fetcher := NewBlockFetcher(node.dial(t), WithBatchSize(4))
+
+	headers, err := fetcher.GetHeadersInRange(context.Background(), 2, 9)
+	if err != nil {
+		t.Fatalf("Failed to get headers in range: %v", err)
+	}
+	if len(headers) != 8 {
+		t.Fatalf("expected 8 headers, got %d", len(headers))
+	}
+	for i, h := range headers {
+		if h.Hash() != node.headers[uint64(i+2)].Hash() {
+			t.Errorf("header %d: hash mismatch", i+2)
+		}
+	}
+	if got := node.callCount("eth_getLogs"); got != 0 {
+		t.Errorf("expected no logs calls, got %d", got)
+	}
+}
@@ -0,0 +1,221 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/KhanSufiyanMirza/evm-indexer-go/internal/metrics"
+)
+
+// ErrCircuitOpen is returned, as a *CircuitOpenError, for calls a
+// CircuitBreaker rejects without sending them.
+var ErrCircuitOpen = errors.New("RPC circuit breaker open")
+
+// CircuitOpenError tells when a rejected call is worth trying again.
+type CircuitOpenError struct {
+	RetryAt time.Time
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("%s until %s", ErrCircuitOpen, e.RetryAt.Format(time.RFC3339))
+}
+
+func (e *CircuitOpenError) Unwrap() error {
+	return ErrCircuitOpen
+}
+
+// BreakerState is the state of a CircuitBreaker, its value is the one of the
+// rpc_circuit_breaker_state gauge.
+type BreakerState int
+
+const (
+	// BreakerClosed lets every call through.
+	BreakerClosed BreakerState = iota
+	// BreakerHalfOpen lets one probe call through at a time to find out
+	// whether the provider recovered.
+	BreakerHalfOpen
+	// BreakerOpen rejects every call until the open timeout has passed.
+	BreakerOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerHalfOpen:
+		return "half-open"
+	case BreakerOpen:
+		return "open"
+	default:
+		return fmt.Sprintf("BreakerState(%d)", int(s))
+	}
+}
+
+// BreakerConfig configures a CircuitBreaker. Zero fields take the value of
+// DefaultBreakerConfig.
+type BreakerConfig struct {
+	// FailureThreshold is how many calls in a row must fail for the breaker to open.
+	FailureThreshold int
+	// SuccessThreshold is how many probes in a row must succeed for the breaker to close again.
+	SuccessThreshold int
+	// OpenTimeout is how long the breaker stays open before probing the provider.
+	OpenTimeout time.Duration
+}
+
+// DefaultBreakerConfig opens after 5 failed calls in a row and probes every 30s.
+var DefaultBreakerConfig = BreakerConfig{
+	FailureThreshold: 5,
+	SuccessThreshold: 1,
+	OpenTimeout:      30 * time.Second,
+}
+
+// halfOpenRetry is when calls rejected because a probe is in flight may try again.
+const halfOpenRetry = time.Second
+
+// CircuitBreaker stops calls to a provider that is down, instead of every
+// caller going through its retries against it. Only failures saying the
+// provider is unavailable count (timeouts, connection errors, 5xx, rate
+// limits), not requests it rejected or callers giving up. Calls are already
+// retried by then, so put it outside the Retry interceptor: wrapped around
+// the fetcher with Intercept, or first in WithInterceptors.
+type CircuitBreaker struct {
+	cfg BreakerConfig
+
+	mu        sync.Mutex
+	state     BreakerState
+	failures  int // calls failed in a row while closed
+	successes int // probes succeeded in a row while half-open
+	probing   bool
+	openUntil time.Time
+}
+
+// NewCircuitBreaker returns a closed CircuitBreaker.
+func NewCircuitBreaker(cfg BreakerConfig) *CircuitBreaker {
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = DefaultBreakerConfig.FailureThreshold
+	}
+	if cfg.SuccessThreshold <= 0 {
+		cfg.SuccessThreshold = DefaultBreakerConfig.SuccessThreshold
+	}
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = DefaultBreakerConfig.OpenTimeout
+	}
+	metrics.RPCCircuitBreakerState.Set(float64(BreakerClosed))
+	return &CircuitBreaker{cfg: cfg}
+}
+
+// State returns the current state of the breaker.
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// Interceptor fails calls with a *CircuitOpenError while the breaker is open.
+func (b *CircuitBreaker) Interceptor() Interceptor {
+	return func(ctx context.Context, call Call, next Handler) (any, error) {
+		probe, err := b.allow()
+		if err != nil {
+			return nil, err
+		}
+		res, err := next(ctx)
+		b.record(probe, err)
+		return res, err
+	}
+}
+
+// allow reports whether a call may be sent, and whether it is a probe.
+func (b *CircuitBreaker) allow() (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	switch b.state {
+	case BreakerOpen:
+		if now.Before(b.openUntil) {
+			return false, &CircuitOpenError{RetryAt: b.openUntil}
+		}
+		b.setState(BreakerHalfOpen)
+		b.successes = 0
+		fallthrough
+	case BreakerHalfOpen:
+		if b.probing {
+			return false, &CircuitOpenError{RetryAt: now.Add(halfOpenRetry)}
+		}
+		b.probing = true
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// record folds the outcome of a call into the breaker's state. Outcomes of
+// calls sent before the breaker opened or went half-open are ignored.
+func (b *CircuitBreaker) record(probe bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if probe {
+		b.probing = false
+	}
+	if errors.Is(err, context.Canceled) {
+		// The caller gave up, nothing learned about the provider.
+		return
+	}
+	failed := isProviderFailure(err)
+
+	switch {
+	case b.state == BreakerClosed && failed:
+		b.failures++
+		if b.failures >= b.cfg.FailureThreshold {
+			b.open()
+		}
+	case b.state == BreakerClosed:
+		b.failures = 0
+	case b.state == BreakerHalfOpen && probe && failed:
+		b.open()
+	case b.state == BreakerHalfOpen && probe:
+		b.successes++
+		if b.successes >= b.cfg.SuccessThreshold {
+			b.failures = 0
+			b.setState(BreakerClosed)
+		}
+	}
+}
+
+// open opens the breaker for OpenTimeout. Callers must hold b.mu.
+func (b *CircuitBreaker) open() {
+	b.openUntil = time.Now().Add(b.cfg.OpenTimeout)
+	b.setState(BreakerOpen)
+}
+
+// setState switches to state, logging and exporting the transition. Callers must hold b.mu.
+func (b *CircuitBreaker) setState(state BreakerState) {
+	from := b.state
+	b.state = state
+	metrics.RPCCircuitBreakerState.Set(float64(state))
+	switch state {
+	case BreakerOpen:
+		slog.Error("ALERT: RPC circuit breaker open, pausing RPC calls", "from", from, "failures", b.failures, "retryIn", b.cfg.OpenTimeout)
+	case BreakerHalfOpen:
+		slog.Info("RPC circuit breaker half-open, probing the provider", "from", from)
+	case BreakerClosed:
+		slog.Info("RPC circuit breaker closed, provider recovered", "from", from)
+	}
+}
+
+// isProviderFailure reports whether err says the provider is unavailable,
+// rather than that it rejected or could not serve this particular request.
+func isProviderFailure(err error) bool {
+	if err == nil || errors.Is(err, ErrCircuitOpen) || errors.Is(err, ErrUnknownBlockHash) {
+		return false
+	}
+	switch ClassifyError(err) {
+	case ClassTransient, ClassRateLimit:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,82 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	b := NewCircuitBreaker(BreakerConfig{FailureThreshold: 3, SuccessThreshold: 2, OpenTimeout: 20 * time.Millisecond})
+	intercept := b.Interceptor()
+	down := errors.New("connection refused")
+	var sent int
+	call := func(err error) error {
+		_, got := intercept(context.Background(), Call{Method: MethodFetch}, func(ctx context.Context) (any, error) {
+			sent++
+			return nil, err
+		})
+		return got
+	}
+	assertState := func(want BreakerState) {
+		t.Helper()
+		if got := b.State(); got != want {
+			t.Fatalf("expected breaker %s, got %s", want, got)
+		}
+	}
+
+	// Errors the provider answers with and successes do not count.
+	call(down)
+	call(errors.New("execution reverted"))
+	call(down)
+	call(nil)
+	call(down)
+	call(down)
+	assertState(BreakerClosed)
+	call(down)
+	assertState(BreakerOpen)
+
+	// Open: calls fail without being sent.
+	sent = 0
+	err := call(nil)
+	var open *CircuitOpenError
+	if !errors.As(err, &open) || !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected a CircuitOpenError, got %v", err)
+	}
+	if sent != 0 {
+		t.Fatal("expected the call not to be sent while open")
+	}
+
+	// A failed probe opens the breaker again.
+	time.Sleep(time.Until(open.RetryAt))
+	call(down)
+	assertState(BreakerOpen)
+
+	// Half-open: one probe at a time, two successes close the breaker.
+	time.Sleep(b.cfg.OpenTimeout)
+	probe := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		_, err := intercept(context.Background(), Call{Method: MethodFetch}, func(ctx context.Context) (any, error) {
+			<-probe
+			return nil, nil
+		})
+		done <- err
+	}()
+	for b.State() != BreakerHalfOpen {
+		time.Sleep(time.Millisecond)
+	}
+	if err := call(nil); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected calls to be rejected while a probe is in flight, got %v", err)
+	}
+	close(probe)
+	if err := <-done; err != nil {
+		t.Fatalf("probe failed: %v", err)
+	}
+	assertState(BreakerHalfOpen)
+	if err := call(nil); err != nil {
+		t.Fatalf("second probe failed: %v", err)
+	}
+	assertState(BreakerClosed)
+}
@@ -0,0 +1,96 @@
+package gateway
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/KhanSufiyanMirza/evm-indexer-go/internal/metrics"
+)
+
+type cacheEntry struct {
+	key     string
+	value   any
+	expires time.Time // zero when the entry never expires
+}
+
+// responseCache is a size-bounded LRU of call results.
+type responseCache struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	entries map[string]*list.Element
+	order   *list.List // most recently used first
+}
+
+// Cache answers repeated calls from memory. Only successful results are kept,
+// at most size of them, the least recently used being evicted first, and each
+// for at most ttl (0 keeps them until evicted). Cached results are shared
+// between callers, who must not modify them.
+//
+// Calls by hash, like GetBlockReceipts, never go stale. Calls by block number
+// or for the chain head change with reorgs and new blocks, so only cache them
+// with a short ttl, e.g. through PerMethod.
+func Cache(size int, ttl time.Duration) Interceptor {
+	c := &responseCache{
+		size:    size,
+		ttl:     ttl,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+	return func(ctx context.Context, call Call, next Handler) (any, error) {
+		key := call.Key()
+		if v, ok := c.get(key); ok {
+			metrics.RPCCacheRequestsTotal.WithLabelValues(call.Method, "hit").Inc()
+			return v, nil
+		}
+		metrics.RPCCacheRequestsTotal.WithLabelValues(call.Method, "miss").Inc()
+		v, err := next(ctx)
+		if err != nil {
+			return v, err
+		}
+		c.put(key, v)
+		return v, nil
+	}
+}
+
+func (c *responseCache) get(key string) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := el.Value.(*cacheEntry)
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		c.order.Remove(el)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return entry.value, true
+}
+
+func (c *responseCache) put(key string, value any) {
+	if c.size <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := &cacheEntry{key: key, value: value}
+	if c.ttl > 0 {
+		entry.expires = time.Now().Add(c.ttl)
+	}
+	if el, ok := c.entries[key]; ok {
+		el.Value = entry
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
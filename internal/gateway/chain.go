@@ -0,0 +1,75 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// ErrChainMismatch is returned when RPC endpoints serve different chains.
+var ErrChainMismatch = errors.New("RPC endpoints serve different chains")
+
+// ChainIdentity identifies the chain an endpoint serves. The chain ID alone is
+// not enough, forks and devnets reuse it, so the genesis hash is kept too.
+type ChainIdentity struct {
+	ChainID     *big.Int
+	GenesisHash common.Hash
+}
+
+func (c ChainIdentity) String() string {
+	return fmt.Sprintf("chain %s (genesis %s)", c.ChainID, c.GenesisHash)
+}
+
+// Equal reports whether c and other are the same chain.
+func (c ChainIdentity) Equal(other ChainIdentity) bool {
+	return c.ChainID.Cmp(other.ChainID) == 0 && c.GenesisHash == other.GenesisHash
+}
+
+// GetChainIdentity asks the node behind client for its chain ID and genesis
+// block hash. The hash is the one the node reports rather than recomputed
+// from the header, which differs on chains whose headers have fields
+// go-ethereum does not know.
+func GetChainIdentity(ctx context.Context, client *ethclient.Client) (ChainIdentity, error) {
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		return ChainIdentity{}, fmt.Errorf("failed to get chain ID: %w", err)
+	}
+	var genesis *struct {
+		Hash common.Hash `json:"hash"`
+	}
+	if err := client.Client().CallContext(ctx, &genesis, "eth_getBlockByNumber", "0x0", false); err != nil {
+		return ChainIdentity{}, fmt.Errorf("failed to get genesis block: %w", err)
+	}
+	if genesis == nil {
+		return ChainIdentity{}, fmt.Errorf("failed to get genesis block: %w", ethereum.NotFound)
+	}
+	return ChainIdentity{ChainID: chainID, GenesisHash: genesis.Hash}, nil
+}
+
+// GetEndpointsChain returns the chain every endpoint serves, and
+// ErrChainMismatch when they do not all serve the same one: the fetcher fails
+// over between them, so one endpoint on another chain would mix chains.
+func GetEndpointsChain(ctx context.Context, endpoints []Endpoint) (ChainIdentity, error) {
+	if len(endpoints) == 0 {
+		return ChainIdentity{}, errors.New("at least one RPC endpoint is required")
+	}
+	var chain ChainIdentity
+	for i, ep := range endpoints {
+		id, err := GetChainIdentity(ctx, ep.Client)
+		if err != nil {
+			return ChainIdentity{}, fmt.Errorf("%s: %w", ep.Name, err)
+		}
+		if i == 0 {
+			chain = id
+		} else if !id.Equal(chain) {
+			return ChainIdentity{}, fmt.Errorf("%w: %s serves %s, %s serves %s",
+				ErrChainMismatch, endpoints[0].Name, chain, ep.Name, id)
+		}
+	}
+	return chain, nil
+}
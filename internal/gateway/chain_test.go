@@ -0,0 +1,36 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestGetEndpointsChain(t *testing.T) {
+	mainnet, replica, fork, other := newTestNode(3), newTestNode(3), newTestNode(3), newTestNode(5)
+	fork.chainID = 2
+	// Another chain with the same chain ID, told apart by its genesis block.
+	other.headers[0].Time++
+
+	ctx := context.Background()
+	chain, err := GetEndpointsChain(ctx, []Endpoint{
+		{Name: "primary", Client: mainnet.dial(t)},
+		{Name: "secondary", Client: replica.dial(t)},
+	})
+	if err != nil {
+		t.Fatalf("expected both endpoints on the same chain, got %v", err)
+	}
+	if chain.ChainID.Uint64() != 1 || chain.GenesisHash != mainnet.headers[0].Hash() {
+		t.Errorf("unexpected chain identity %s", chain)
+	}
+
+	for name, node := range map[string]*testNode{"chain ID": fork, "genesis": other} {
+		_, err := GetEndpointsChain(ctx, []Endpoint{
+			{Name: "primary", Client: mainnet.dial(t)},
+			{Name: "secondary", Client: node.dial(t)},
+		})
+		if !errors.Is(err, ErrChainMismatch) {
+			t.Errorf("%s: expected ErrChainMismatch, got %v", name, err)
+		}
+	}
+}
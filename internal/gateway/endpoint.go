@@ -0,0 +1,225 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/KhanSufiyanMirza/evm-indexer-go/internal/metrics"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+const (
+	// ewmaAlpha is the weight given to the newest sample when updating the
+	// moving averages of latency and error rate.
+	ewmaAlpha = 0.3
+	// maxConsecutiveFailures puts an endpoint into cooldown once reached.
+	maxConsecutiveFailures = 3
+	// endpointCooldown is how long an endpoint is skipped after too many failures.
+	endpointCooldown = 30 * time.Second
+)
+
+// Endpoint is a named RPC provider the fetcher can route calls to.
+// Name is used as the metrics label, so it must not contain secrets (API keys).
+type Endpoint struct {
+	Name   string
+	Client *ethclient.Client
+}
+
+// DialEndpoints dials every url and names each endpoint after its host.
+// On failure, every client dialed so far is closed.
+func DialEndpoints(rawurls []string) ([]Endpoint, error) {
+	endpoints := make([]Endpoint, 0, len(rawurls))
+	seen := make(map[string]int, len(rawurls))
+	for _, rawurl := range rawurls {
+		client, err := ethclient.Dial(rawurl)
+		if err != nil {
+			for _, ep := range endpoints {
+				ep.Client.Close()
+			}
+			return nil, fmt.Errorf("failed to dial %s: %w", endpointName(rawurl), err)
+		}
+		name := endpointName(rawurl)
+		seen[name]++
+		if seen[name] > 1 {
+			// Same host with different paths/keys, keep labels unique.
+			name = fmt.Sprintf("%s-%d", name, seen[name])
+		}
+		endpoints = append(endpoints, Endpoint{Name: name, Client: client})
+	}
+	return endpoints, nil
+}
+
+// endpointName strips everything but the host from rawurl so that API keys
+// embedded in paths or query strings never end up in logs or metrics.
+func endpointName(rawurl string) string {
+	u, err := url.Parse(rawurl)
+	if err != nil || u.Host == "" {
+		return "unknown"
+	}
+	return u.Host
+}
+
+// endpointState tracks the health of a single endpoint.
+type endpointState struct {
+	Endpoint
+
+	mu                  sync.Mutex
+	latency             float64 // EWMA of call latency in seconds
+	errorRate           float64 // EWMA of failures, 0 (healthy) to 1 (always failing)
+	consecutiveFailures int
+	cooldownUntil       time.Time
+	logSpan             uint64       // learned max block span per eth_getLogs call, see logs.go
+	logSpanStreak       int          // small results in a row at the full logSpan
+	limiter             *rateLimiter // nil when no rate limit is configured
+	noBlockReceipts     bool         // eth_getBlockReceipts is not supported, see receipts.go
+}
+
+// score returns the health of the endpoint between 0 (unusable) and 1 (perfect).
+// Callers must hold s.mu.
+func (s *endpointState) score() float64 {
+	return (1 - s.errorRate) / (1 + s.latency)
+}
+
+// record folds the outcome of a call into the endpoint's health. Only
+// errors saying the endpoint itself is unwell (timeouts, dropped connections,
+// 5xx) or is throttling us (rate limits) count as failures, so calls move to
+// another endpoint. Any other answer, errors included (a range too large, a
+// block the node has not seen yet, an unsupported method), came from a
+// working endpoint and is due to the request.
+func (s *endpointState) record(d time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.latency = ewmaAlpha*d.Seconds() + (1-ewmaAlpha)*s.latency
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	if class := ClassifyError(err); class == ClassTransient || class == ClassRateLimit {
+		s.errorRate = ewmaAlpha + (1-ewmaAlpha)*s.errorRate
+		s.consecutiveFailures++
+		if s.consecutiveFailures >= maxConsecutiveFailures {
+			s.cooldownUntil = time.Now().Add(endpointCooldown)
+			slog.Warn("RPC endpoint in cooldown", "endpoint", s.Name, "failures", s.consecutiveFailures, "cooldown", endpointCooldown)
+		}
+	} else {
+		s.errorRate = (1 - ewmaAlpha) * s.errorRate
+		s.consecutiveFailures = 0
+		s.cooldownUntil = time.Time{}
+	}
+
+	metrics.RPCEndpointRequestsTotal.WithLabelValues(s.Name, result).Inc()
+	metrics.RPCEndpointLatency.WithLabelValues(s.Name).Observe(d.Seconds())
+	metrics.RPCEndpointHealthScore.WithLabelValues(s.Name).Set(s.score())
+}
+
+// endpointPool routes calls to the healthiest endpoint.
+type endpointPool struct {
+	endpoints []*endpointState
+}
+
+func newEndpointPool(endpoints []Endpoint) *endpointPool {
+	pool := &endpointPool{endpoints: make([]*endpointState, 0, len(endpoints))}
+	for _, ep := range endpoints {
+		pool.endpoints = append(pool.endpoints, &endpointState{Endpoint: ep, logSpan: initialLogSpan})
+		metrics.RPCEndpointHealthScore.WithLabelValues(ep.Name).Set(1)
+		metrics.RPCLogSpan.WithLabelValues(ep.Name).Set(initialLogSpan)
+	}
+	return pool
+}
+
+// pick returns the endpoint with the best score, skipping endpoints that are
+// cooling down or were already tried for the current call. If every endpoint
+// is excluded it falls back to the best of the cooling-down ones, and then to
+// the best overall, so a call is always attempted.
+// Ties go to the endpoint listed first, which keeps the primary preferred.
+func (p *endpointPool) pick(tried map[*endpointState]bool) *endpointState {
+	now := time.Now()
+	var best, bestCooling, bestAny *endpointState
+	var bestScore, bestCoolingScore, bestAnyScore float64
+	for _, ep := range p.endpoints {
+		ep.mu.Lock()
+		score := ep.score()
+		cooling := now.Before(ep.cooldownUntil)
+		ep.mu.Unlock()
+
+		if bestAny == nil || score > bestAnyScore {
+			bestAny, bestAnyScore = ep, score
+		}
+		if tried[ep] {
+			continue
+		}
+		if cooling {
+			if bestCooling == nil || score > bestCoolingScore {
+				bestCooling, bestCoolingScore = ep, score
+			}
+			continue
+		}
+		if best == nil || score > bestScore {
+			best, bestScore = ep, score
+		}
+	}
+	if best != nil {
+		return best
+	}
+	if bestCooling != nil {
+		return bestCooling
+	}
+	return bestAny
+}
+
+// triedEndpoints are the endpoints the attempts of one call went to, shared
+// by the requests the call makes concurrently.
+type triedEndpoints struct {
+	mu  sync.Mutex
+	set map[*endpointState]bool
+}
+
+func newTriedEndpoints() *triedEndpoints {
+	return &triedEndpoints{set: make(map[*endpointState]bool)}
+}
+
+// reset forgets the tried endpoints, the next request of the call starts from
+// the healthiest endpoint again.
+func (t *triedEndpoints) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	clear(t.set)
+}
+
+// rateLimitWaitKey holds, in the context of a call to one endpoint, the time
+// the call waited for rate limit budget, see endpointState.wait.
+type rateLimitWaitKey struct{}
+
+// callEndpoint runs fn against the healthiest endpoint not yet in tried and
+// records the outcome against that endpoint. The endpoint is added to tried so
+// a retry of the same call fails over to another provider. The latency
+// recorded leaves out the time fn waited for rate limit budget.
+func callEndpoint[T any](ctx context.Context, pool *endpointPool, tried *triedEndpoints, fn func(context.Context, *endpointState) (T, error)) (T, error) {
+	tried.mu.Lock()
+	ep := pool.pick(tried.set)
+	if len(tried.set) > 0 && !tried.set[ep] {
+		slog.Warn("Failing over to RPC endpoint", "endpoint", ep.Name)
+		metrics.RPCEndpointFailoversTotal.WithLabelValues(ep.Name).Inc()
+	}
+	tried.set[ep] = true
+	tried.mu.Unlock()
+
+	waited := new(atomic.Int64)
+	st := time.Now()
+	res, err := fn(context.WithValue(ctx, rateLimitWaitKey{}, waited), ep)
+	// A cancelled caller says nothing about the endpoint's health.
+	if !errors.Is(err, context.Canceled) || ctx.Err() == nil {
+		ep.record(time.Since(st)-time.Duration(waited.Load()), err)
+	}
+	if err != nil {
+		return res, fmt.Errorf("%s: %w", ep.Name, err)
+	}
+	return res, nil
+}
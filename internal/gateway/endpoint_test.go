@@ -0,0 +1,156 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// errUnavailable is an error of the endpoint itself, a 503.
+var errUnavailable = rpc.HTTPError{StatusCode: http.StatusServiceUnavailable, Status: "503 Service Unavailable"}
+
+func TestEndpointPoolPick(t *testing.T) {
+	pool := newEndpointPool([]Endpoint{{Name: "primary"}, {Name: "secondary"}})
+	primary, secondary := pool.endpoints[0], pool.endpoints[1]
+
+	if got := pool.pick(map[*endpointState]bool{}); got != primary {
+		t.Fatalf("expected primary on tie, got %s", got.Name)
+	}
+
+	// A call that already tried the primary fails over to the secondary.
+	if got := pool.pick(map[*endpointState]bool{primary: true}); got != secondary {
+		t.Fatalf("expected failover to secondary, got %s", got.Name)
+	}
+
+	// Errors lower the primary's score below the secondary's.
+	primary.record(10*time.Millisecond, errUnavailable)
+	secondary.record(10*time.Millisecond, nil)
+	if got := pool.pick(map[*endpointState]bool{}); got != secondary {
+		t.Fatalf("expected healthier secondary, got %s", got.Name)
+	}
+}
+
+func TestEndpointCooldown(t *testing.T) {
+	pool := newEndpointPool([]Endpoint{{Name: "primary"}, {Name: "secondary"}})
+	primary, secondary := pool.endpoints[0], pool.endpoints[1]
+
+	for range maxConsecutiveFailures {
+		secondary.record(time.Millisecond, errUnavailable)
+	}
+	// Even a slow primary beats an endpoint in cooldown.
+	primary.record(5*time.Second, nil)
+	if got := pool.pick(map[*endpointState]bool{}); got != primary {
+		t.Fatalf("expected primary while secondary cools down, got %s", got.Name)
+	}
+
+	// With the primary already tried, the cooling endpoint is still used rather than nothing.
+	if got := pool.pick(map[*endpointState]bool{primary: true}); got != secondary {
+		t.Fatalf("expected cooling secondary as last resort, got %s", got.Name)
+	}
+
+	// A success clears the cooldown.
+	secondary.record(time.Millisecond, nil)
+	secondary.mu.Lock()
+	cooling := time.Now().Before(secondary.cooldownUntil)
+	secondary.mu.Unlock()
+	if cooling {
+		t.Fatal("expected cooldown to be cleared after a success")
+	}
+}
+
+func TestEndpointPoolAvoidsRateLimitedEndpoint(t *testing.T) {
+	pool := newEndpointPool([]Endpoint{{Name: "primary"}, {Name: "secondary"}})
+	primary, secondary := pool.endpoints[0], pool.endpoints[1]
+
+	primary.record(time.Millisecond, testRPCError{429, "Too Many Requests"})
+	secondary.record(time.Millisecond, nil)
+	if got := pool.pick(map[*endpointState]bool{}); got != secondary {
+		t.Fatalf("expected the secondary over a throttled primary, got %s", got.Name)
+	}
+
+	// Throttled over and over, the primary cools down like a failing one.
+	for range maxConsecutiveFailures - 1 {
+		primary.record(time.Millisecond, testRPCError{codeLimitExceeded, "rate limit exceeded"})
+	}
+	primary.mu.Lock()
+	cooling := time.Now().Before(primary.cooldownUntil)
+	primary.mu.Unlock()
+	if !cooling {
+		t.Fatal("expected a rate limited endpoint to cool down")
+	}
+}
+
+func TestEndpointRecordIgnoresRequestErrors(t *testing.T) {
+	pool := newEndpointPool([]Endpoint{{Name: "primary"}})
+	primary := pool.endpoints[0]
+
+	// Answers to a bad request leave the endpoint healthy.
+	for _, err := range []error{
+		testRPCError{codeLimitExceeded, "query returned more than 10000 results"},
+		ethereum.NotFound,
+		ErrBlockTagUnsupported,
+		testRPCError{codeMethodNotFound, "the method eth_getBlockReceipts does not exist"},
+	} {
+		for range maxConsecutiveFailures {
+			primary.record(time.Millisecond, err)
+		}
+		primary.mu.Lock()
+		failures, errorRate := primary.consecutiveFailures, primary.errorRate
+		primary.mu.Unlock()
+		if failures != 0 || errorRate != 0 {
+			t.Errorf("%v: expected no failure recorded, got %d failures and error rate %v", err, failures, errorRate)
+		}
+	}
+
+	primary.record(time.Millisecond, fmt.Errorf("eth_getLogs: %w", context.DeadlineExceeded))
+	primary.mu.Lock()
+	failures := primary.consecutiveFailures
+	primary.mu.Unlock()
+	if failures != 1 {
+		t.Errorf("expected a timeout to count as a failure, got %d failures", failures)
+	}
+}
+
+func TestCallEndpointLatencyExcludesRateLimitWait(t *testing.T) {
+	pool := newEndpointPool([]Endpoint{{Name: "primary"}})
+	primary := pool.endpoints[0]
+	primary.limiter = newRateLimiter(RateLimit{RequestsPerSecond: 1})
+	primary.limiter.requests.reserve(1) // the next request waits a second
+
+	_, err := callEndpoint(context.Background(), pool, newTriedEndpoints(), func(ctx context.Context, ep *endpointState) (bool, error) {
+		return true, ep.wait(ctx, "eth_blockNumber")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	primary.mu.Lock()
+	latency := primary.latency
+	primary.mu.Unlock()
+	if latency > 0.1 {
+		t.Errorf("expected the rate limit wait to be left out of the latency, got %.2fs", latency/ewmaAlpha)
+	}
+}
+
+func TestCallEndpointConcurrentRequests(t *testing.T) {
+	pool := newEndpointPool([]Endpoint{{Name: "primary"}, {Name: "secondary"}})
+	tried := newTriedEndpoints()
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = callEndpoint(context.Background(), pool, tried, func(ctx context.Context, ep *endpointState) (bool, error) {
+				return false, errors.New("boom")
+			})
+			tried.reset()
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,206 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"syscall"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// ErrBlockTagUnsupported is returned when the node does not serve the "safe"
+// or "finalized" block tag, as on chains without a finality gadget or
+// pre-merge nodes. Callers fall back to a confirmation depth.
+var ErrBlockTagUnsupported = errors.New("block tag not supported by the node")
+
+// ErrorClass groups RPC errors by how the caller should react to them.
+// The class is also the "type" label of metrics.RPCErrorsTotal.
+type ErrorClass string
+
+const (
+	// ClassRateLimit means the provider throttled us, retry after backing off.
+	ClassRateLimit ErrorClass = "rate_limit"
+	// ClassNodeLag means the node has not seen the requested block yet, retry.
+	ClassNodeLag ErrorClass = "node_lag"
+	// ClassRangeTooLarge means the request spans too many blocks or results.
+	// Retrying the same request is pointless, it has to be split.
+	ClassRangeTooLarge ErrorClass = "range_too_large"
+	// ClassTransient covers timeouts, dropped connections and 5xx responses, retry.
+	ClassTransient ErrorClass = "transient"
+	// ClassFatal covers everything retrying will not fix (bad params, unsupported method, ...).
+	ClassFatal ErrorClass = "fatal"
+)
+
+// Retryable reports whether repeating the same request may succeed.
+func (c ErrorClass) Retryable() bool {
+	switch c {
+	case ClassRateLimit, ClassNodeLag, ClassTransient:
+		return true
+	default:
+		return false
+	}
+}
+
+// JSON-RPC error codes used by geth and the common hosted providers.
+const (
+	codeLimitExceeded       = -32005 // rate limit, or "query returned more than 10000 results"
+	codeOverRateLimit       = -32016
+	codeResourceNotFound    = -32001 // node lag
+	codeResourceUnavailable = -32002 // geth: request timed out
+	codeResponseTooLarge    = -32003
+	codeInternal            = -32603
+	codeServerError         = -32000 // generic, needs the message to classify
+	codeMethodNotFound      = -32601
+)
+
+// ClassifyError maps an RPC error to its ErrorClass using, in order, the
+// JSON-RPC error code, the HTTP status, network error types and finally the
+// error message for providers that only report errors as text.
+func ClassifyError(err error) ErrorClass {
+	if err == nil {
+		return ""
+	}
+	if errors.Is(err, ErrBlockTagUnsupported) {
+		return ClassFatal
+	}
+
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) {
+		switch rpcErr.ErrorCode() {
+		case codeOverRateLimit:
+			return ClassRateLimit
+		case codeLimitExceeded:
+			// Infura and others reuse -32005 for oversized log queries.
+			if class := classifyMessage(rpcErr.Error()); class == ClassRangeTooLarge {
+				return class
+			}
+			return ClassRateLimit
+		case codeResourceNotFound:
+			return ClassNodeLag
+		case codeResourceUnavailable, codeInternal:
+			return ClassTransient
+		case codeResponseTooLarge:
+			return ClassRangeTooLarge
+		case codeServerError:
+			if class := classifyMessage(rpcErr.Error()); class != "" {
+				return class
+			}
+			return ClassFatal
+		}
+	}
+
+	var httpErr rpc.HTTPError
+	if errors.As(err, &httpErr) {
+		switch {
+		case httpErr.StatusCode == http.StatusTooManyRequests:
+			return ClassRateLimit
+		case httpErr.StatusCode == http.StatusRequestEntityTooLarge:
+			return ClassRangeTooLarge
+		case httpErr.StatusCode == http.StatusRequestTimeout || httpErr.StatusCode >= 500:
+			return ClassTransient
+		}
+		// Some providers answer 4xx with a JSON-RPC style message in the body.
+		if class := classifyMessage(string(httpErr.Body)); class != "" {
+			return class
+		}
+		return ClassFatal
+	}
+
+	// ethclient reports a null block as NotFound, the node is behind.
+	if errors.Is(err, ethereum.NotFound) {
+		return ClassNodeLag
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ClassTransient
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ClassTransient
+	}
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return ClassTransient
+	}
+
+	if class := classifyMessage(err.Error()); class != "" {
+		return class
+	}
+	return ClassFatal
+}
+
+// rangeTooLargeMessages are how providers reject an eth_getLogs query over
+// too many blocks or returning too many logs.
+var rangeTooLargeMessages = []string{
+	"query returned more than",      // geth, Infura: "query returned more than 10000 results"
+	"log response size exceeded",    // Alchemy
+	"block range is too wide",       // Ankr
+	"block range too large",         // Besu
+	"exceed maximum block range",    // BSC, PublicNode: "exceed maximum block range: 5000"
+	"eth_getlogs is limited to",     // QuickNode: "eth_getLogs is limited to a 10,000 range"
+	"query exceeds max results",     // Erigon
+	"query exceeds max block range", // Erigon
+	"requested too many blocks",     // op-geth: "requested too many blocks from 0 to 20000, maximum is set to 10000"
+}
+
+// httpStatusMessage matches the status codes of rate limits and unavailable
+// gateways quoted in a message, as whole numbers: block numbers and hashes
+// in the same message must not match.
+var httpStatusMessage = regexp.MustCompile(`\b(429|502|503|504)\b`)
+
+// classifyMessage classifies an error by its text. It returns "" when the
+// message matches no known pattern. Rate limits and transient failures are
+// matched first: a throttled or failing call says nothing about its range.
+func classifyMessage(msg string) ErrorClass {
+	msg = strings.ToLower(msg)
+	status := httpStatusMessage.FindString(msg)
+
+	switch {
+	case containsAny(msg, "rate limit", "too many requests", "exceeded the quota", "capacity exceeded", "compute units"), status == "429":
+		return ClassRateLimit
+	case containsAny(msg, "timeout", "timed out", "context deadline", "no response", "connection reset by peer", "connection refused"), status != "":
+		return ClassTransient
+	case containsAny(msg, rangeTooLargeMessages...):
+		return ClassRangeTooLarge
+	case containsAny(msg, "header not found", "unknown block", "block not found"),
+		// geth's debug_traceBlockByHash: "block 0x… not found".
+		strings.HasPrefix(msg, "block 0x") && strings.HasSuffix(msg, " not found"):
+		return ClassNodeLag
+	default:
+		return ""
+	}
+}
+
+// isMethodNotFound reports whether err says the endpoint does not support the
+// called method, so the caller can fall back to another one.
+func isMethodNotFound(err error) bool {
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) && rpcErr.ErrorCode() == codeMethodNotFound {
+		return true
+	}
+	return containsAny(strings.ToLower(err.Error()), "method not found", "does not exist", "not supported", "unsupported method", "not available")
+}
+
+// isBlockTagUnsupported reports whether err is how nodes without finality
+// answer a request for the "safe" or "finalized" block: a null block, "safe
+// block not found" (geth before the merge) or a rejected parameter.
+func isBlockTagUnsupported(err error) bool {
+	if errors.Is(err, ethereum.NotFound) {
+		return true
+	}
+	return containsAny(strings.ToLower(err.Error()), "safe block not found", "finalized block not found", "invalid block tag", "unknown block tag", "invalid argument")
+}
+
+func containsAny(s string, substrs ...string) bool {
+	for _, sub := range substrs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
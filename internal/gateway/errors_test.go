@@ -0,0 +1,60 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want ErrorClass
+	}{
+		{"nil", nil, ""},
+		{"over rate limit", testRPCError{-32016, "over rate limit"}, ClassRateLimit},
+		{"limit exceeded", testRPCError{-32005, "limit exceeded"}, ClassRateLimit},
+		{"too many results", testRPCError{-32005, "query returned more than 10000 results"}, ClassRangeTooLarge},
+		{"resource not found", testRPCError{-32001, "resource not found"}, ClassNodeLag},
+		{"internal error", testRPCError{-32603, "internal error"}, ClassTransient},
+		{"header not found", testRPCError{-32000, "header not found"}, ClassNodeLag},
+		{"unknown block hash", testRPCError{-32000, "block 0x1f2e not found"}, ClassNodeLag},
+		{"block range", testRPCError{-32000, "block range is too wide"}, ClassRangeTooLarge},
+		{"alchemy log response size", testRPCError{-32602, "Log response size exceeded. You can make eth_getLogs requests with up to a 2K block range"}, ClassRangeTooLarge},
+		{"infura too many results", testRPCError{-32005, "query returned more than 10000 results. Try with this block range [0x1502a, 0x1503f]."}, ClassRangeTooLarge},
+		{"besu block range", testRPCError{-32000, "block range too large"}, ClassRangeTooLarge},
+		{"bsc block range", testRPCError{-32000, "exceed maximum block range: 5000"}, ClassRangeTooLarge},
+		{"quicknode block range", testRPCError{-32000, "eth_getLogs is limited to a 10,000 range"}, ClassRangeTooLarge},
+		{"erigon max results", testRPCError{-32000, "query exceeds max results 20000"}, ClassRangeTooLarge},
+		{"erigon max block range", testRPCError{-32000, "query exceeds max block range 1000"}, ClassRangeTooLarge},
+		{"op-geth too many blocks", testRPCError{-32000, "requested too many blocks from 0 to 20000, maximum is set to 10000"}, ClassRangeTooLarge},
+		{"unrelated limit", testRPCError{-32000, "gas limit reached"}, ClassFatal},
+		{"unrelated too many", testRPCError{-32000, "too many arguments, want at most 1"}, ClassFatal},
+		{"rate limit before range", testRPCError{-32000, "rate limit exceeded, block range too large for your plan"}, ClassRateLimit},
+		{"timeout before range", testRPCError{-32000, "Query timeout exceeded. Consider reducing your block range"}, ClassTransient},
+		{"quoted 429", testRPCError{-32000, "upstream answered 429"}, ClassRateLimit},
+		{"quoted 502", testRPCError{-32000, "upstream answered 502 bad gateway"}, ClassTransient},
+		{"execution reverted", testRPCError{-32000, "execution reverted"}, ClassFatal},
+		{"invalid params", testRPCError{-32602, "invalid argument 0"}, ClassFatal},
+		{"http 429", rpc.HTTPError{StatusCode: 429, Status: "429 Too Many Requests"}, ClassRateLimit},
+		{"http 503", rpc.HTTPError{StatusCode: 503, Status: "503 Service Unavailable"}, ClassTransient},
+		{"http 401", rpc.HTTPError{StatusCode: 401, Status: "401 Unauthorized"}, ClassFatal},
+		{"wrapped", fmt.Errorf("endpoint: %w", testRPCError{-32016, "slow down"}), ClassRateLimit},
+		{"null block", ethereum.NotFound, ClassNodeLag},
+		{"deadline", context.DeadlineExceeded, ClassTransient},
+		{"plain text timeout", errors.New("i/o timeout"), ClassTransient},
+		{"unknown", errors.New("something odd"), ClassFatal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClassifyError(tt.err); got != tt.want {
+				t.Errorf("ClassifyError(%v) = %q, want %q", tt.err, got, tt.want)
+			}
+		})
+	}
+}
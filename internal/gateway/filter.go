@@ -0,0 +1,134 @@
+package gateway
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// LogFilter selects logs by the contract that emitted them and their topics,
+// with the semantics of eth_getLogs: a log matches when its address is one of
+// Addresses and, at every position of Topics, its topic is one of the listed
+// ones. An empty list matches anything.
+type LogFilter struct {
+	Addresses []common.Address
+	Topics    [][]common.Hash
+}
+
+// ERC20TransferFilter matches Transfer(address,address,uint256) logs of any
+// contract. ERC721 transfers share the signature and match too.
+func ERC20TransferFilter() LogFilter {
+	return LogFilter{Topics: [][]common.Hash{{erc20TransferEventHash}}}
+}
+
+// Matches reports whether the filter selects log.
+func (f LogFilter) Matches(log types.Log) bool {
+	if len(f.Addresses) > 0 && !slices.Contains(f.Addresses, log.Address) {
+		return false
+	}
+	for i, topics := range f.Topics {
+		if len(topics) == 0 {
+			continue
+		}
+		if i >= len(log.Topics) || !slices.Contains(topics, log.Topics[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// MergeLogFilters returns filters matching every log that any of filters
+// matches, with as few queries as the filters allow. Filters on the same
+// contracts are merged into one, with the first topics (the event
+// signatures) of all of them, so the result may match more logs of those
+// contracts than the filters do; each consumer picks its logs out of the
+// result with its own filter's Matches. Filters on different contracts are
+// kept apart, merged they would match the events of one on the contracts of
+// the other, and any event of any contract next to a filter on all
+// contracts, unless they select the same events: those are one filter on all
+// their contracts.
+func MergeLogFilters(filters ...LogFilter) []LogFilter {
+	var merged []LogFilter
+	for _, f := range filters {
+		addresses := slices.Clone(f.Addresses)
+		slices.SortFunc(addresses, func(a, b common.Address) int { return a.Cmp(b) })
+		addresses = slices.Compact(addresses)
+		i := slices.IndexFunc(merged, func(m LogFilter) bool { return slices.Equal(m.Addresses, addresses) })
+		if i < 0 {
+			merged = append(merged, LogFilter{Addresses: addresses, Topics: [][]common.Hash{{}}})
+			i = len(merged) - 1
+		}
+		m := &merged[i]
+		switch {
+		case m.Topics == nil:
+			// Already any event of these contracts.
+		case len(f.Topics) == 0 || len(f.Topics[0]) == 0:
+			m.Topics = nil
+		default:
+			for _, t := range f.Topics[0] {
+				if !slices.Contains(m.Topics[0], t) {
+					m.Topics[0] = append(m.Topics[0], t)
+				}
+			}
+		}
+	}
+
+	var queries []LogFilter
+	for _, m := range merged {
+		if m.Topics != nil {
+			slices.SortFunc(m.Topics[0], func(a, b common.Hash) int { return a.Cmp(b) })
+		}
+		i := slices.IndexFunc(queries, func(q LogFilter) bool {
+			return len(q.Addresses) > 0 && len(m.Addresses) > 0 && slices.EqualFunc(q.Topics, m.Topics, slices.Equal)
+		})
+		if i < 0 {
+			queries = append(queries, m)
+			continue
+		}
+		q := &queries[i]
+		q.Addresses = append(q.Addresses, m.Addresses...)
+		slices.SortFunc(q.Addresses, func(a, b common.Address) int { return a.Cmp(b) })
+		q.Addresses = slices.Compact(q.Addresses)
+	}
+	return queries
+}
+
+// matchesAny reports whether any of filters selects log.
+func matchesAny(filters []LogFilter, log types.Log) bool {
+	return slices.ContainsFunc(filters, func(f LogFilter) bool { return f.Matches(log) })
+}
+
+// mergeLogs returns the logs of sets in chain order, each once: the queries
+// of several filters return the logs they all match once per filter.
+func mergeLogs(sets ...[]types.Log) []types.Log {
+	if len(sets) == 1 {
+		return sets[0]
+	}
+	logs := []types.Log{}
+	for _, set := range sets {
+		logs = append(logs, set...)
+	}
+	slices.SortFunc(logs, func(a, b types.Log) int {
+		if a.BlockNumber != b.BlockNumber {
+			return cmp.Compare(a.BlockNumber, b.BlockNumber)
+		}
+		return cmp.Compare(a.Index, b.Index)
+	})
+	return slices.CompactFunc(logs, func(a, b types.Log) bool {
+		return a.BlockNumber == b.BlockNumber && a.Index == b.Index
+	})
+}
+
+// query returns the filter as eth_getLogs parameters, without the block range.
+func (f LogFilter) query() map[string]any {
+	query := make(map[string]any)
+	if len(f.Addresses) > 0 {
+		query["address"] = f.Addresses
+	}
+	if len(f.Topics) > 0 {
+		query["topics"] = f.Topics
+	}
+	return query
+}
@@ -0,0 +1,69 @@
+package gateway
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestMergeLogFilters(t *testing.T) {
+	approval := crypto.Keccak256Hash([]byte("Approval(address,address,uint256)"))
+	swap := crypto.Keccak256Hash([]byte("Swap(address,uint256,uint256,uint256,uint256,address)"))
+	pool := common.HexToAddress("0xbeef")
+	token := common.HexToAddress("0xcafe")
+	owner := common.HexToHash("0xa11ce")
+
+	transfers := ERC20TransferFilter()
+	approvals := LogFilter{Addresses: []common.Address{token}, Topics: [][]common.Hash{{approval}, {owner}}}
+	swaps := LogFilter{Addresses: []common.Address{pool}, Topics: [][]common.Hash{{swap}}}
+
+	logs := map[string]types.Log{
+		"transfer":        {Address: pool, Topics: []common.Hash{erc20TransferEventHash, {}, {}}},
+		"owner approval":  {Address: token, Topics: []common.Hash{approval, owner, {}}},
+		"other approval":  {Address: token, Topics: []common.Hash{approval, {}, {}}},
+		"foreign swap":    {Address: token, Topics: []common.Hash{swap}},
+		"swap":            {Address: pool, Topics: []common.Hash{swap}},
+		"anonymous event": {Address: pool},
+	}
+	poolTransfers := LogFilter{Addresses: []common.Address{pool}, Topics: [][]common.Hash{{erc20TransferEventHash}}}
+	for _, tc := range []struct {
+		name    string
+		filters []LogFilter
+		queries int
+		matches []string
+	}{
+		{"transfers", MergeLogFilters(transfers), 1, []string{"transfer"}},
+		// Every filter on the same contracts widens the others' signatures.
+		{"swaps and transfers of the pool", MergeLogFilters(swaps, poolTransfers), 1, []string{"transfer", "swap"}},
+		// Events of several contracts are not matched on each other's contracts.
+		{"approvals and swaps", MergeLogFilters(approvals, swaps), 2, []string{"owner approval", "other approval", "swap"}},
+		{"all three", MergeLogFilters(transfers, approvals, swaps), 3, []string{"transfer", "owner approval", "other approval", "swap"}},
+		// The same events of several contracts are one query on all of them.
+		{"swaps of two pools", MergeLogFilters(swaps, LogFilter{Addresses: []common.Address{token}, Topics: [][]common.Hash{{swap}}}), 1, []string{"foreign swap", "swap"}},
+		{"nothing to merge", MergeLogFilters(), 0, nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if len(tc.filters) != tc.queries {
+				t.Errorf("expected %d filters, got %+v", tc.queries, tc.filters)
+			}
+			for name, log := range logs {
+				want := slices.Contains(tc.matches, name)
+				if got := matchesAny(tc.filters, log); got != want {
+					t.Errorf("%s: expected match %v, got %v", name, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestMergeLogs(t *testing.T) {
+	log := func(block uint64, index uint) types.Log { return types.Log{BlockNumber: block, Index: index} }
+	got := mergeLogs([]types.Log{log(1, 0), log(2, 3)}, []types.Log{log(1, 2), log(2, 3)}, nil)
+	want := []types.Log{log(1, 0), log(1, 2), log(2, 3)}
+	if !slices.EqualFunc(got, want, func(a, b types.Log) bool { return a.BlockNumber == b.BlockNumber && a.Index == b.Index }) {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
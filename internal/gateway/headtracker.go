@@ -0,0 +1,130 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/KhanSufiyanMirza/evm-indexer-go/internal/metrics"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// staleHeadFactor is how many poll intervals a subscription may stay silent
+// before it is considered dead and the tracker falls back to polling.
+const staleHeadFactor = 5
+
+var errStaleSubscription = errors.New("no new heads received on subscription")
+
+// HeadTracker reports new chain tip heights as soon as they are known.
+// It subscribes to newHeads when one of the endpoints supports subscriptions
+// (ws or ipc) and polls GetBlockNumber otherwise, or while the
+// subscription is down.
+type HeadTracker struct {
+	fetcher      BlockFetcher
+	subscriber   *Endpoint
+	pollInterval time.Duration
+	heads        chan uint64
+	last         uint64
+}
+
+// NewHeadTracker returns a HeadTracker that subscribes through the first
+// endpoint supporting subscriptions and polls through fetcher otherwise.
+func NewHeadTracker(fetcher BlockFetcher, endpoints []Endpoint, pollInterval time.Duration) *HeadTracker {
+	t := &HeadTracker{
+		fetcher:      fetcher,
+		pollInterval: pollInterval,
+		heads:        make(chan uint64, 1),
+	}
+	for _, ep := range endpoints {
+		if ep.Client.Client().SupportsSubscriptions() {
+			t.subscriber = &ep
+			break
+		}
+	}
+	return t
+}
+
+// Heads returns the channel new tip heights are delivered on. Only the latest
+// height is kept, so a slow reader never sees stale tips queued up.
+func (t *HeadTracker) Heads() <-chan uint64 {
+	return t.heads
+}
+
+// Run tracks the chain head until ctx is cancelled.
+func (t *HeadTracker) Run(ctx context.Context) {
+	if t.subscriber == nil {
+		slog.Info("No endpoint supports subscriptions, polling for new heads", "pollInterval", t.pollInterval)
+	}
+	for ctx.Err() == nil {
+		if t.subscriber != nil {
+			err := t.subscribe(ctx, t.subscriber.Client)
+			if ctx.Err() != nil {
+				return
+			}
+			slog.Warn("newHeads subscription down, falling back to polling", "endpoint", t.subscriber.Name, "error", err)
+			metrics.HeadSubscriptionActive.Set(0)
+		}
+		// Poll once right away so no head is missed, then keep polling
+		// until it's time to try subscribing again.
+		t.poll(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(t.pollInterval):
+		}
+	}
+}
+
+// subscribe delivers heads from a newHeads subscription until it fails or goes silent.
+func (t *HeadTracker) subscribe(ctx context.Context, client *ethclient.Client) error {
+	ch := make(chan *types.Header, 16)
+	sub, err := client.SubscribeNewHead(ctx, ch)
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+	slog.Info("Subscribed to newHeads", "endpoint", t.subscriber.Name)
+	metrics.HeadSubscriptionActive.Set(1)
+
+	staleAfter := staleHeadFactor * t.pollInterval
+	stale := time.NewTimer(staleAfter)
+	defer stale.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-sub.Err():
+			return err
+		case <-stale.C:
+			return errStaleSubscription
+		case header := <-ch:
+			t.publish(header.Number.Uint64())
+			stale.Reset(staleAfter)
+		}
+	}
+}
+
+func (t *HeadTracker) poll(ctx context.Context) {
+	latest, err := t.fetcher.GetBlockNumber(ctx)
+	if err != nil {
+		slog.Error("Failed to poll latest block number", "error", err)
+		return
+	}
+	t.publish(latest)
+}
+
+// publish delivers height if it advances the tip, replacing any height the
+// reader has not picked up yet.
+func (t *HeadTracker) publish(height uint64) {
+	if height <= t.last {
+		return
+	}
+	t.last = height
+	select {
+	case <-t.heads:
+	default:
+	}
+	t.heads <- height
+}
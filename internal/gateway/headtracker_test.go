@@ -0,0 +1,29 @@
+package gateway
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestHeadTrackerFallsBackToPolling(t *testing.T) {
+	node := newTestNode(5)
+	client := node.dial(t)
+	// The in-process client supports subscriptions but the test node has no
+	// newHeads, so the tracker has to fall back to polling.
+	endpoints := []Endpoint{{Name: "inproc", Client: client}}
+	tracker := NewHeadTracker(NewBlockFetcher(client), endpoints, 10*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go tracker.Run(ctx)
+
+	select {
+	case head := <-tracker.Heads():
+		if head != 4 {
+			t.Fatalf("expected head 4, got %d", head)
+		}
+	case <-ctx.Done():
+		t.Fatal("no head delivered")
+	}
+}
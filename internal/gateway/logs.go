@@ -0,0 +1,139 @@
+package gateway
+
+import (
+	"context"
+	"log/slog"
+	"math/big"
+
+	"github.com/KhanSufiyanMirza/evm-indexer-go/internal/metrics"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	// initialLogSpan is the block span tried per eth_getLogs call before
+	// anything is learned about the provider.
+	initialLogSpan = 2000
+	// maxLogSpan caps how far the learned span can grow.
+	maxLogSpan = 100_000
+	// smallLogResult is the result size under which a span is considered
+	// comfortably within the provider's limits.
+	smallLogResult = 1000
+	// logSpanGrowAfter is how many small results in a row at the full span
+	// it takes to double the span, so it does not bounce straight back
+	// after a split.
+	logSpanGrowAfter = 5
+)
+
+// logChunk is the part of a range one endpoint call of filterLogs fetched.
+type logChunk struct {
+	logs []types.Log
+	to   uint64
+}
+
+// logRange is what earlier attempts of a filterLogs call fetched.
+type logRange struct {
+	logs []types.Log
+	next uint64
+}
+
+// filterLogs fetches the logs matching query from startBlock to endBlock, as
+// the given part of a call making several queries.
+// The range is walked in chunks no larger than the serving endpoint's learned
+// span; a chunk the endpoint rejects as too large is bisected until it fits,
+// and the span shrinks accordingly. Spans grow back while results stay small.
+// An attempt that fails part way is resumed by the next attempt of the same
+// call, so an error late in a long range does not refetch what was already
+// fetched.
+func (bf *blockFetcher) filterLogs(ctx context.Context, part int, query ethereum.FilterQuery, startBlock, endBlock uint64) ([]types.Log, error) {
+	progress := scopeValue(ctx, scopeKey{bf: bf, name: "logs", from: startBlock, to: endBlock, part: part}, func() *logRange {
+		return &logRange{next: startBlock}
+	})
+	tried := bf.tried(ctx)
+	for progress.next <= endBlock {
+		from := progress.next
+		chunk, err := callEndpoint(ctx, bf.pool, tried, func(ctx context.Context, ep *endpointState) (logChunk, error) {
+			to := min(endBlock, from+ep.getLogSpan()-1)
+			logs, err := ep.filterLogsBisect(ctx, query, from, to)
+			return logChunk{logs: logs, to: to}, err
+		})
+		if err != nil {
+			return nil, err
+		}
+		progress.logs = append(progress.logs, chunk.logs...)
+		progress.next = chunk.to + 1
+		// Each chunk starts from the healthiest endpoint again.
+		tried.reset()
+	}
+	return progress.logs, nil
+}
+
+// filterLogsBisect fetches logs for from..to from this endpoint, splitting the
+// range in half and recursing whenever the endpoint rejects it as too large.
+func (s *endpointState) filterLogsBisect(ctx context.Context, query ethereum.FilterQuery, from, to uint64) ([]types.Log, error) {
+	query.FromBlock = new(big.Int).SetUint64(from)
+	query.ToBlock = new(big.Int).SetUint64(to)
+	if err := s.wait(ctx, "eth_getLogs"); err != nil {
+		return nil, err
+	}
+	logs, err := s.Client.FilterLogs(ctx, query)
+	if err == nil {
+		s.observeLogSpan(to-from+1, len(logs))
+		return logs, nil
+	}
+	if ClassifyError(err) != ClassRangeTooLarge || from == to {
+		return nil, err
+	}
+
+	mid := from + (to-from)/2
+	s.shrinkLogSpan(mid - from + 1)
+	slog.Warn("Log range too large, splitting", "endpoint", s.Name, "startBlock", from, "endBlock", to, "error", err)
+	metrics.RPCLogRangeSplitsTotal.WithLabelValues(s.Name).Inc()
+
+	left, err := s.filterLogsBisect(ctx, query, from, mid)
+	if err != nil {
+		return nil, err
+	}
+	right, err := s.filterLogsBisect(ctx, query, mid+1, to)
+	if err != nil {
+		return nil, err
+	}
+	return append(left, right...), nil
+}
+
+func (s *endpointState) getLogSpan() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.logSpan
+}
+
+// shrinkLogSpan lowers the learned span to at most span.
+func (s *endpointState) shrinkLogSpan(span uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.logSpanStreak = 0
+	if span < s.logSpan {
+		s.logSpan = span
+		metrics.RPCLogSpan.WithLabelValues(s.Name).Set(float64(span))
+	}
+}
+
+// observeLogSpan doubles the learned span after enough calls over the full
+// span came back with few results.
+func (s *endpointState) observeLogSpan(span uint64, results int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if span < s.logSpan || s.logSpan >= maxLogSpan {
+		return
+	}
+	if results >= smallLogResult {
+		s.logSpanStreak = 0
+		return
+	}
+	s.logSpanStreak++
+	if s.logSpanStreak >= logSpanGrowAfter {
+		s.logSpanStreak = 0
+		s.logSpan = min(2*s.logSpan, maxLogSpan)
+		metrics.RPCLogSpan.WithLabelValues(s.Name).Set(float64(s.logSpan))
+	}
+}
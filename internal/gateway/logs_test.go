@@ -0,0 +1,83 @@
+package gateway
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestGetERC20TransfersInRangeSplitsLargeRanges(t *testing.T) {
+	node := newTestNode(100)
+	node.logSpanLimit = 10
+	fetcher := newBlockFetcher([]Endpoint{{Name: "default", Client: node.dial(t)}}, nil)
+
+	logs, err := fetcher.GetERC20TransfersInRange(context.Background(), 0, 99)
+	if err != nil {
+		t.Fatalf("Failed to get ERC20 transfers in range: %v", err)
+	}
+	if len(logs) != 100 {
+		t.Fatalf("expected 100 logs, got %d", len(logs))
+	}
+	for i, log := range logs {
+		if log.BlockNumber != uint64(i) {
+			t.Fatalf("log %d: expected block %d, got %d", i, i, log.BlockNumber)
+		}
+	}
+	if span := fetcher.pool.endpoints[0].getLogSpan(); span > 10 {
+		t.Errorf("expected learned span <= 10, got %d", span)
+	}
+
+}
+
+func TestGetERC20TransfersInRangeQueriesEveryFilter(t *testing.T) {
+	node := newTestNode(10)
+	token := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	vault := common.HexToAddress("0x00000000000000000000000000000000000000bb")
+	deposit := common.HexToHash("0xde")
+	for num := range uint64(10) {
+		node.logs[num] = append(node.logs[num], types.Log{Address: vault, Topics: []common.Hash{deposit}, BlockNumber: num, Index: 1})
+	}
+	filters := MergeLogFilters(
+		LogFilter{Addresses: []common.Address{token}, Topics: [][]common.Hash{{erc20TransferEventHash}}},
+		LogFilter{Addresses: []common.Address{vault}, Topics: [][]common.Hash{{deposit}}},
+	)
+	// Through the retry interceptor, where every query keeps its own progress.
+	fetcher := NewBlockFetcher(node.dial(t), WithLogFilters(filters...))
+
+	logs, err := fetcher.GetERC20TransfersInRange(context.Background(), 0, 9)
+	if err != nil {
+		t.Fatalf("Failed to get logs in range: %v", err)
+	}
+	if calls := node.callCount("eth_getLogs"); calls != 2 {
+		t.Errorf("expected a query per filter, got %d", calls)
+	}
+	if len(logs) != 20 {
+		t.Fatalf("expected the 10 logs of each filter, got %d", len(logs))
+	}
+	for i, log := range logs {
+		if want := []common.Address{token, vault}[i%2]; log.BlockNumber != uint64(i/2) || log.Address != want {
+			t.Errorf("log %d: expected block %d of %s, got %+v", i, i/2, want, log)
+		}
+	}
+}
+
+func TestLogSpanGrowsBackAfterSmallResults(t *testing.T) {
+	pool := newEndpointPool([]Endpoint{{Name: "primary"}})
+	ep := pool.endpoints[0]
+	ep.shrinkLogSpan(100)
+
+	for range logSpanGrowAfter - 1 {
+		ep.observeLogSpan(100, 10)
+	}
+	// A narrower call does not count towards growing the span.
+	ep.observeLogSpan(50, 10)
+	if span := ep.getLogSpan(); span != 100 {
+		t.Fatalf("expected span to stay at 100, got %d", span)
+	}
+	ep.observeLogSpan(100, 10)
+	if span := ep.getLogSpan(); span != 200 {
+		t.Fatalf("expected span to double to 200, got %d", span)
+	}
+}
@@ -0,0 +1,118 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/KhanSufiyanMirza/evm-indexer-go/internal/metrics"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// ErrNoSubscriptions is returned when a feature needs a subscription (ws or
+// ipc) endpoint and none of the configured endpoints supports them.
+var ErrNoSubscriptions = errors.New("no endpoint supports subscriptions")
+
+// pendingBuffer is how many pending transactions may wait for the reader
+// before new ones are discarded.
+const pendingBuffer = 4096
+
+// PendingTx is a transaction announced by a node's mempool.
+type PendingTx struct {
+	Tx   *types.Transaction
+	From common.Address
+}
+
+// PendingTxWatcher streams the transactions entering the mempool, from a
+// newPendingTransactions subscription with full transaction objects. Unlike
+// new heads there is nothing to poll, so while the subscription is down
+// pending transactions are simply not seen.
+type PendingTxWatcher struct {
+	subscriber    *Endpoint
+	retryInterval time.Duration
+	txs           chan PendingTx
+}
+
+// NewPendingTxWatcher returns a PendingTxWatcher subscribing through the first
+// endpoint supporting subscriptions, and resubscribing retryInterval after
+// the subscription fails.
+func NewPendingTxWatcher(endpoints []Endpoint, retryInterval time.Duration) (*PendingTxWatcher, error) {
+	for _, ep := range endpoints {
+		if ep.Client.Client().SupportsSubscriptions() {
+			return &PendingTxWatcher{
+				subscriber:    &ep,
+				retryInterval: retryInterval,
+				txs:           make(chan PendingTx, pendingBuffer),
+			}, nil
+		}
+	}
+	return nil, ErrNoSubscriptions
+}
+
+// Pending returns the channel pending transactions are delivered on. When the
+// reader falls behind, transactions that don't fit the buffer are discarded
+// rather than holding up the subscription.
+func (w *PendingTxWatcher) Pending() <-chan PendingTx {
+	return w.txs
+}
+
+// Run watches the mempool until ctx is cancelled.
+func (w *PendingTxWatcher) Run(ctx context.Context) {
+	for ctx.Err() == nil {
+		err := w.subscribe(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		slog.Warn("newPendingTransactions subscription down, resubscribing", "endpoint", w.subscriber.Name, "retryIn", w.retryInterval, "error", err)
+		metrics.PendingTxSubscriptionActive.Set(0)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(w.retryInterval):
+		}
+	}
+}
+
+// subscribe delivers pending transactions until the subscription fails.
+func (w *PendingTxWatcher) subscribe(ctx context.Context) error {
+	ch := make(chan *types.Transaction, 256)
+	sub, err := w.subscriber.Client.Client().EthSubscribe(ctx, ch, "newPendingTransactions", true)
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+	slog.Info("Subscribed to newPendingTransactions", "endpoint", w.subscriber.Name)
+	metrics.PendingTxSubscriptionActive.Set(1)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-sub.Err():
+			return err
+		case tx := <-ch:
+			from, err := TxSender(tx)
+			if err != nil {
+				slog.Debug("Skipping pending transaction with invalid signature", "txHash", tx.Hash(), "error", err)
+				continue
+			}
+			select {
+			case w.txs <- PendingTx{Tx: tx, From: from}:
+				metrics.PendingTransactionsTotal.WithLabelValues("seen").Inc()
+			default:
+				metrics.PendingTransactionsTotal.WithLabelValues("discarded").Inc()
+			}
+		}
+	}
+}
+
+// TxSender recovers the address that signed tx.
+func TxSender(tx *types.Transaction) (common.Address, error) {
+	if !tx.Protected() {
+		// Legacy transactions signed before EIP-155 carry no chain ID.
+		return types.Sender(types.HomesteadSigner{}, tx)
+	}
+	return types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+}
@@ -0,0 +1,52 @@
+package gateway
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestPendingTxWatcher(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	to := common.HexToAddress("0x02")
+	signer := types.LatestSignerForChainID(big.NewInt(1))
+	dynamic := types.MustSignNewTx(key, signer, &types.DynamicFeeTx{
+		ChainID: big.NewInt(1), Nonce: 7, GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(2), Gas: 21_000, To: &to, Value: big.NewInt(5),
+	})
+	legacy := types.MustSignNewTx(key, types.HomesteadSigner{}, &types.LegacyTx{
+		Nonce: 8, GasPrice: big.NewInt(3), Gas: 21_000, To: &to, Value: big.NewInt(6),
+	})
+
+	node := newTestNode(1)
+	node.pending = []*types.Transaction{dynamic, legacy}
+	watcher, err := NewPendingTxWatcher([]Endpoint{{Name: "inproc", Client: node.dial(t)}}, time.Second)
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go watcher.Run(ctx)
+
+	for _, want := range node.pending {
+		select {
+		case got := <-watcher.Pending():
+			if got.Tx.Hash() != want.Hash() {
+				t.Errorf("expected transaction %s, got %s", want.Hash(), got.Tx.Hash())
+			}
+			if got.From != sender {
+				t.Errorf("expected sender %s, got %s", sender, got.From)
+			}
+		case <-ctx.Done():
+			t.Fatal("pending transaction not delivered")
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/KhanSufiyanMirza/evm-indexer-go/internal/metrics"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// BlockFetcher method names, as seen by interceptors in Call.Method.
+const (
+	MethodFetch                    = "Fetch"
+	MethodFetchHeader              = "FetchHeader"
+	MethodGetBlockNumber           = "GetBlockNumber"
+	MethodGetTaggedBlockNumber     = "GetTaggedBlockNumber"
+	MethodGetLogsInRange           = "GetLogsInRange"
+	MethodGetERC20TransfersInRange = "GetERC20TransfersInRange"
+	MethodGetBlocksInRange         = "GetBlocksInRange"
+	MethodGetHeadersInRange        = "GetHeadersInRange"
+	MethodGetBlockReceipts         = "GetBlockReceipts"
+	MethodGetInternalTransfers     = "GetInternalTransfers"
+)
+
+// Call is one BlockFetcher method call.
+type Call struct {
+	Method string
+	Args   []any
+}
+
+// Key identifies the call by method and arguments.
+func (c Call) Key() string {
+	return fmt.Sprint(c.Method, c.Args)
+}
+
+// Handler performs a call, or the rest of the interceptor chain.
+type Handler func(ctx context.Context) (any, error)
+
+// Interceptor wraps every call of a BlockFetcher, e.g. to retry, log or cache
+// it. It either calls next or answers the call itself.
+type Interceptor func(ctx context.Context, call Call, next Handler) (any, error)
+
+// DefaultInterceptors is the chain NewBlockFetcher wraps calls with unless
+// WithInterceptors says otherwise.
+func DefaultInterceptors() []Interceptor {
+	return []Interceptor{Logging(), Metrics(), Retry(DefaultRetryPolicy)}
+}
+
+// WithInterceptors replaces the default interceptor chain. The first
+// interceptor is the outermost one.
+func WithInterceptors(interceptors ...Interceptor) Option {
+	return func(bf *blockFetcher) {
+		bf.interceptors = interceptors
+	}
+}
+
+// PerMethod applies the interceptor configured for the call's method in
+// overrides, or def for any other method. A nil interceptor passes the call
+// straight through.
+func PerMethod(def Interceptor, overrides map[string]Interceptor) Interceptor {
+	return func(ctx context.Context, call Call, next Handler) (any, error) {
+		interceptor, ok := overrides[call.Method]
+		if !ok {
+			interceptor = def
+		}
+		if interceptor == nil {
+			return next(ctx)
+		}
+		return interceptor(ctx, call, next)
+	}
+}
+
+// Logging logs every call with its duration.
+func Logging() Interceptor {
+	return func(ctx context.Context, call Call, next Handler) (any, error) {
+		st := time.Now()
+		res, err := next(ctx)
+		if err != nil {
+			slog.Warn("RPC call failed", "method", call.Method, "args", call.Args, "duration", time.Since(st), "error", err)
+			return res, err
+		}
+		slog.Info("RPC call done", "method", call.Method, "args", call.Args, "duration", time.Since(st))
+		return res, nil
+	}
+}
+
+// Metrics records the latency of every call, retries included, per method.
+func Metrics() Interceptor {
+	return func(ctx context.Context, call Call, next Handler) (any, error) {
+		st := time.Now()
+		res, err := next(ctx)
+		result := "success"
+		if err != nil {
+			result = "error"
+		}
+		metrics.RPCMethodDuration.WithLabelValues(call.Method, result).Observe(time.Since(st).Seconds())
+		return res, err
+	}
+}
+
+// Intercept wraps every method of f with interceptors, the first one being
+// the outermost.
+func Intercept(f BlockFetcher, interceptors ...Interceptor) BlockFetcher {
+	if len(interceptors) == 0 {
+		return f
+	}
+	chain := func(ctx context.Context, call Call, h Handler) (any, error) {
+		return h(ctx)
+	}
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, inner := interceptors[i], chain
+		chain = func(ctx context.Context, call Call, h Handler) (any, error) {
+			return interceptor(ctx, call, func(ctx context.Context) (any, error) {
+				return inner(ctx, call, h)
+			})
+		}
+	}
+	return &interceptedFetcher{next: f, chain: chain}
+}
+
+// interceptedFetcher routes each BlockFetcher method through the chain.
+type interceptedFetcher struct {
+	next  BlockFetcher
+	chain func(ctx context.Context, call Call, h Handler) (any, error)
+}
+
+func invoke[T any](ctx context.Context, f *interceptedFetcher, call Call, fn func(ctx context.Context) (T, error)) (T, error) {
+	res, err := f.chain(ctx, call, func(ctx context.Context) (any, error) {
+		return fn(ctx)
+	})
+	v, _ := res.(T)
+	return v, err
+}
+
+func (f *interceptedFetcher) Fetch(ctx context.Context, blockNumber uint64) (*types.Block, error) {
+	return invoke(ctx, f, Call{Method: MethodFetch, Args: []any{blockNumber}}, func(ctx context.Context) (*types.Block, error) {
+		return f.next.Fetch(ctx, blockNumber)
+	})
+}
+
+func (f *interceptedFetcher) FetchHeader(ctx context.Context, blockNumber uint64) (*types.Header, error) {
+	return invoke(ctx, f, Call{Method: MethodFetchHeader, Args: []any{blockNumber}}, func(ctx context.Context) (*types.Header, error) {
+		return f.next.FetchHeader(ctx, blockNumber)
+	})
+}
+
+func (f *interceptedFetcher) GetBlockNumber(ctx context.Context) (uint64, error) {
+	return invoke(ctx, f, Call{Method: MethodGetBlockNumber}, func(ctx context.Context) (uint64, error) {
+		return f.next.GetBlockNumber(ctx)
+	})
+}
+
+func (f *interceptedFetcher) GetTaggedBlockNumber(ctx context.Context, tag rpc.BlockNumber) (uint64, error) {
+	return invoke(ctx, f, Call{Method: MethodGetTaggedBlockNumber, Args: []any{tag}}, func(ctx context.Context) (uint64, error) {
+		return f.next.GetTaggedBlockNumber(ctx, tag)
+	})
+}
+
+func (f *interceptedFetcher) GetLogsInRange(ctx context.Context, startBlock, endBlock uint64) ([]types.Log, error) {
+	return invoke(ctx, f, Call{Method: MethodGetLogsInRange, Args: []any{startBlock, endBlock}}, func(ctx context.Context) ([]types.Log, error) {
+		return f.next.GetLogsInRange(ctx, startBlock, endBlock)
+	})
+}
+
+func (f *interceptedFetcher) GetERC20TransfersInRange(ctx context.Context, startBlock, endBlock uint64) ([]types.Log, error) {
+	return invoke(ctx, f, Call{Method: MethodGetERC20TransfersInRange, Args: []any{startBlock, endBlock}}, func(ctx context.Context) ([]types.Log, error) {
+		return f.next.GetERC20TransfersInRange(ctx, startBlock, endBlock)
+	})
+}
+
+func (f *interceptedFetcher) GetBlocksInRange(ctx context.Context, startBlock, endBlock uint64) ([]BlockLogs, error) {
+	return invoke(ctx, f, Call{Method: MethodGetBlocksInRange, Args: []any{startBlock, endBlock}}, func(ctx context.Context) ([]BlockLogs, error) {
+		return f.next.GetBlocksInRange(ctx, startBlock, endBlock)
+	})
+}
+
+func (f *interceptedFetcher) GetHeadersInRange(ctx context.Context, startBlock, endBlock uint64) ([]*types.Header, error) {
+	return invoke(ctx, f, Call{Method: MethodGetHeadersInRange, Args: []any{startBlock, endBlock}}, func(ctx context.Context) ([]*types.Header, error) {
+		return f.next.GetHeadersInRange(ctx, startBlock, endBlock)
+	})
+}
+
+func (f *interceptedFetcher) GetBlockReceipts(ctx context.Context, blockHash common.Hash) (types.Receipts, error) {
+	return invoke(ctx, f, Call{Method: MethodGetBlockReceipts, Args: []any{blockHash}}, func(ctx context.Context) (types.Receipts, error) {
+		return f.next.GetBlockReceipts(ctx, blockHash)
+	})
+}
+
+func (f *interceptedFetcher) GetInternalTransfers(ctx context.Context, blockHash common.Hash) ([]InternalTransfer, error) {
+	return invoke(ctx, f, Call{Method: MethodGetInternalTransfers, Args: []any{blockHash}}, func(ctx context.Context) ([]InternalTransfer, error) {
+		return f.next.GetInternalTransfers(ctx, blockHash)
+	})
+}
@@ -0,0 +1,97 @@
+package gateway
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestInterceptOrder(t *testing.T) {
+	node := newTestNode(5)
+	var order []string
+	trace := func(name string) Interceptor {
+		return func(ctx context.Context, call Call, next Handler) (any, error) {
+			order = append(order, name+">"+call.Key())
+			res, err := next(ctx)
+			order = append(order, name+"<")
+			return res, err
+		}
+	}
+	fetcher := NewBlockFetcher(node.dial(t), WithInterceptors(trace("outer"), trace("inner")))
+
+	header, err := fetcher.FetchHeader(context.Background(), 3)
+	if err != nil {
+		t.Fatalf("Failed to fetch header: %v", err)
+	}
+	if header.Number.Uint64() != 3 {
+		t.Fatalf("expected header 3, got %d", header.Number.Uint64())
+	}
+	want := []string{"outer>FetchHeader[3]", "inner>FetchHeader[3]", "inner<", "outer<"}
+	if !slices.Equal(order, want) {
+		t.Errorf("expected %v, got %v", want, order)
+	}
+}
+
+func TestPerMethodRetry(t *testing.T) {
+	fastRetry := Retry(RetryPolicy{InitialInterval: time.Millisecond})
+	noRetry := Retry(RetryPolicy{MaxTries: 1})
+	interceptor := PerMethod(fastRetry, map[string]Interceptor{MethodGetBlocksInRange: noRetry})
+
+	node := newTestNode(5)
+	node.failLogs[2] = 1
+	fetcher := NewBlockFetcher(node.dial(t), WithInterceptors(interceptor))
+	if _, err := fetcher.GetBlocksInRange(context.Background(), 1, 4); err == nil {
+		t.Fatal("expected GetBlocksInRange to fail without retries")
+	}
+
+	node = newTestNode(5)
+	node.failLogs[2] = 1
+	fetcher = NewBlockFetcher(node.dial(t), WithInterceptors(interceptor))
+	logs, err := fetcher.GetERC20TransfersInRange(context.Background(), 1, 4)
+	if err != nil {
+		t.Fatalf("expected GetERC20TransfersInRange to be retried: %v", err)
+	}
+	if len(logs) != 4 {
+		t.Errorf("expected 4 logs, got %d", len(logs))
+	}
+}
+
+func TestCache(t *testing.T) {
+	node := newTestNode(5)
+	fetcher := NewBlockFetcher(node.dial(t), WithInterceptors(Cache(1, 0)))
+	ctx := context.Background()
+	first, second := node.headers[1].Hash(), node.headers[2].Hash()
+
+	for _, hash := range []common.Hash{first, first, second, first} {
+		receipts, err := fetcher.GetBlockReceipts(ctx, hash)
+		if err != nil {
+			t.Fatalf("Failed to get receipts of %s: %v", hash, err)
+		}
+		if len(receipts) == 0 || receipts[0].BlockHash != hash {
+			t.Fatalf("expected receipts of %s, got %+v", hash, receipts)
+		}
+	}
+	// The repeated call is served from the cache, the last one was evicted by the second block.
+	if got := node.callCount("eth_getBlockReceipts"); got != 3 {
+		t.Errorf("expected 3 eth_getBlockReceipts calls, got %d", got)
+	}
+}
+
+func TestCacheExpires(t *testing.T) {
+	node := newTestNode(5)
+	fetcher := NewBlockFetcher(node.dial(t), WithInterceptors(Cache(10, time.Millisecond)))
+	ctx := context.Background()
+
+	for range 2 {
+		if _, err := fetcher.GetBlockNumber(ctx); err != nil {
+			t.Fatalf("Failed to get block number: %v", err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := node.callCount("eth_blockNumber"); got != 2 {
+		t.Errorf("expected the expired entry to be refetched, got %d calls", got)
+	}
+}
@@ -0,0 +1,365 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/KhanSufiyanMirza/evm-indexer-go/internal/metrics"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+var (
+	// ErrQuorumMismatch is returned in halt mode when providers disagree
+	// with the primary and too few of them agree to reach the quorum.
+	ErrQuorumMismatch = errors.New("providers disagree on block data")
+	// ErrQuorumUnavailable is returned in halt mode when too few providers
+	// answered to reach the quorum.
+	ErrQuorumUnavailable = errors.New("not enough providers answered to reach quorum")
+)
+
+// MismatchPolicy decides what happens when the quorum is not reached.
+type MismatchPolicy string
+
+const (
+	// MismatchHalt fails the call so nothing unverified gets ingested.
+	MismatchHalt MismatchPolicy = "halt"
+	// MismatchRecord records the discrepancy and carries on with the primary's data.
+	MismatchRecord MismatchPolicy = "record"
+)
+
+// Kinds of data cross-checked between providers.
+const (
+	DiscrepancyBlockHash = "block_hash"
+	DiscrepancyLogCount  = "log_count"
+)
+
+// Discrepancy describes providers disagreeing about one block.
+type Discrepancy struct {
+	BlockNumber   uint64
+	Kind          string            // DiscrepancyBlockHash or DiscrepancyLogCount
+	Expected      string            // the primary's value
+	Observed      map[string]string // verifier name -> the value it returned
+	QuorumReached bool
+}
+
+// DiscrepancyRecorder persists discrepancies for later investigation.
+type DiscrepancyRecorder interface {
+	RecordDiscrepancy(ctx context.Context, d Discrepancy) error
+}
+
+// DiscrepancyRecorderFunc adapts a function to a DiscrepancyRecorder.
+type DiscrepancyRecorderFunc func(ctx context.Context, d Discrepancy) error
+
+func (f DiscrepancyRecorderFunc) RecordDiscrepancy(ctx context.Context, d Discrepancy) error {
+	return f(ctx, d)
+}
+
+// QuorumConfig configures NewQuorumFetcher.
+type QuorumConfig struct {
+	// Quorum is how many providers, the primary included, must agree on a
+	// value. Zero means a majority of the primary and all verifiers.
+	Quorum   int
+	Policy   MismatchPolicy
+	Recorder DiscrepancyRecorder // optional
+}
+
+type verifier struct {
+	name    string
+	fetcher BlockFetcher
+}
+
+// quorumFetcher cross-checks block hashes and per-block log counts returned by
+// the primary against independent verifier providers.
+// Methods it does not override go straight to the primary.
+type quorumFetcher struct {
+	BlockFetcher
+	verifiers []verifier
+	quorum    int
+	policy    MismatchPolicy
+	recorder  DiscrepancyRecorder
+}
+
+// NewQuorumFetcher wraps primary so that every block hash and log
+// count it returns from Fetch, FetchHeader, GetERC20TransfersInRange, GetBlocksInRange
+// and GetHeadersInRange is checked against each of the verifier endpoints. Every verifier gets its
+// own fetcher built with opts.
+func NewQuorumFetcher(primary BlockFetcher, verifiers []Endpoint, cfg QuorumConfig, opts ...Option) (BlockFetcher, error) {
+	if len(verifiers) == 0 {
+		return nil, errors.New("at least one verifier endpoint is required")
+	}
+	quorum := cfg.Quorum
+	if quorum == 0 {
+		quorum = (len(verifiers)+1)/2 + 1
+	}
+	if quorum < 1 || quorum > len(verifiers)+1 {
+		return nil, fmt.Errorf("quorum %d out of range, have %d providers", quorum, len(verifiers)+1)
+	}
+	policy := cfg.Policy
+	if policy == "" {
+		policy = MismatchHalt
+	}
+	if policy != MismatchHalt && policy != MismatchRecord {
+		return nil, fmt.Errorf("unknown mismatch policy %q", policy)
+	}
+
+	qf := &quorumFetcher{
+		BlockFetcher: primary,
+		quorum:       quorum,
+		policy:       policy,
+		recorder:     cfg.Recorder,
+	}
+	for _, ep := range verifiers {
+		qf.verifiers = append(qf.verifiers, verifier{
+			name:    ep.Name,
+			fetcher: newBlockFetcher([]Endpoint{ep}, opts).intercepted(),
+		})
+	}
+	slog.Info("Quorum verification enabled", "verifiers", len(verifiers), "quorum", quorum, "onMismatch", policy)
+	return qf, nil
+}
+
+func (qf *quorumFetcher) Fetch(ctx context.Context, blockNumber uint64) (*types.Block, error) {
+	block, err := qf.BlockFetcher.Fetch(ctx, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	if err := qf.verifyBlockHash(ctx, blockNumber, block.Hash()); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+func (qf *quorumFetcher) FetchHeader(ctx context.Context, blockNumber uint64) (*types.Header, error) {
+	header, err := qf.BlockFetcher.FetchHeader(ctx, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	if err := qf.verifyBlockHash(ctx, blockNumber, header.Hash()); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
+
+// verifyBlockHash checks the primary's hash of one block against the verifiers' headers.
+func (qf *quorumFetcher) verifyBlockHash(ctx context.Context, blockNumber uint64, hash common.Hash) error {
+	expected := map[uint64]string{blockNumber: hash.String()}
+	observed := qf.observe(ctx, func(f BlockFetcher) (map[uint64]string, error) {
+		header, err := f.FetchHeader(ctx, blockNumber)
+		if err != nil {
+			return nil, err
+		}
+		return map[uint64]string{blockNumber: header.Hash().String()}, nil
+	})
+	return qf.verify(ctx, DiscrepancyBlockHash, expected, observed)
+}
+
+func (qf *quorumFetcher) GetERC20TransfersInRange(ctx context.Context, startBlock, endBlock uint64) ([]types.Log, error) {
+	logs, err := qf.BlockFetcher.GetERC20TransfersInRange(ctx, startBlock, endBlock)
+	if err != nil {
+		return nil, err
+	}
+	expected := logCounts(startBlock, endBlock, logs)
+	observed := qf.observe(ctx, func(f BlockFetcher) (map[uint64]string, error) {
+		logs, err := f.GetERC20TransfersInRange(ctx, startBlock, endBlock)
+		if err != nil {
+			return nil, err
+		}
+		return logCounts(startBlock, endBlock, logs), nil
+	})
+	if err := qf.verify(ctx, DiscrepancyLogCount, expected, observed); err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
+
+func (qf *quorumFetcher) GetBlocksInRange(ctx context.Context, startBlock, endBlock uint64) ([]BlockLogs, error) {
+	blocks, err := qf.BlockFetcher.GetBlocksInRange(ctx, startBlock, endBlock)
+	if err != nil {
+		return nil, err
+	}
+	observedBlocks := make([][]BlockLogs, len(qf.verifiers))
+	observedHashes := qf.observeIndexed(ctx, func(i int, f BlockFetcher) (map[uint64]string, error) {
+		blocks, err := f.GetBlocksInRange(ctx, startBlock, endBlock)
+		if err != nil {
+			return nil, err
+		}
+		observedBlocks[i] = blocks
+		return blockHashes(blocks), nil
+	})
+	if err := qf.verify(ctx, DiscrepancyBlockHash, blockHashes(blocks), observedHashes); err != nil {
+		return nil, err
+	}
+
+	observedCounts := make([]observation, len(qf.verifiers))
+	for i, o := range observedHashes {
+		observedCounts[i] = observation{name: o.name, err: o.err}
+		if o.err == nil {
+			observedCounts[i].values = blockLogCounts(observedBlocks[i])
+		}
+	}
+	if err := qf.verify(ctx, DiscrepancyLogCount, blockLogCounts(blocks), observedCounts); err != nil {
+		return nil, err
+	}
+	return blocks, nil
+}
+
+func (qf *quorumFetcher) GetHeadersInRange(ctx context.Context, startBlock, endBlock uint64) ([]*types.Header, error) {
+	headers, err := qf.BlockFetcher.GetHeadersInRange(ctx, startBlock, endBlock)
+	if err != nil {
+		return nil, err
+	}
+	observed := qf.observe(ctx, func(f BlockFetcher) (map[uint64]string, error) {
+		headers, err := f.GetHeadersInRange(ctx, startBlock, endBlock)
+		if err != nil {
+			return nil, err
+		}
+		return headerHashes(headers), nil
+	})
+	if err := qf.verify(ctx, DiscrepancyBlockHash, headerHashes(headers), observed); err != nil {
+		return nil, err
+	}
+	return headers, nil
+}
+
+// observation is what one verifier returned, keyed by block number.
+type observation struct {
+	name   string
+	values map[uint64]string
+	err    error
+}
+
+// observe asks every verifier concurrently.
+func (qf *quorumFetcher) observe(ctx context.Context, fn func(BlockFetcher) (map[uint64]string, error)) []observation {
+	return qf.observeIndexed(ctx, func(_ int, f BlockFetcher) (map[uint64]string, error) {
+		return fn(f)
+	})
+}
+
+func (qf *quorumFetcher) observeIndexed(ctx context.Context, fn func(int, BlockFetcher) (map[uint64]string, error)) []observation {
+	observations := make([]observation, len(qf.verifiers))
+	var wg sync.WaitGroup
+	for i, v := range qf.verifiers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			values, err := fn(i, v.fetcher)
+			observations[i] = observation{name: v.name, values: values, err: err}
+		}()
+	}
+	wg.Wait()
+	return observations
+}
+
+// verify counts, per block, how many providers agree with the primary.
+// Verifiers that failed to answer abstain.
+func (qf *quorumFetcher) verify(ctx context.Context, kind string, expected map[uint64]string, observed []observation) error {
+	for _, o := range observed {
+		if o.err != nil {
+			slog.Warn("Verifier failed to answer", "verifier", o.name, "kind", kind, "error", o.err)
+		}
+	}
+
+	numbers := make([]uint64, 0, len(expected))
+	for num := range expected {
+		numbers = append(numbers, num)
+	}
+	slices.Sort(numbers)
+
+	for _, num := range numbers {
+		agree := 1 // the primary
+		disagree := make(map[string]string)
+		for _, o := range observed {
+			if o.err != nil {
+				continue
+			}
+			value, ok := o.values[num]
+			if !ok {
+				continue
+			}
+			if value == expected[num] {
+				agree++
+			} else {
+				disagree[o.name] = value
+			}
+		}
+
+		reached := agree >= qf.quorum
+		if len(disagree) > 0 {
+			metrics.QuorumDiscrepanciesTotal.WithLabelValues(kind).Inc()
+			slog.Warn("Providers disagree", "block", num, "kind", kind, "expected", expected[num], "observed", disagree, "agree", agree, "quorum", qf.quorum)
+			if qf.recorder != nil {
+				err := qf.recorder.RecordDiscrepancy(ctx, Discrepancy{
+					BlockNumber:   num,
+					Kind:          kind,
+					Expected:      expected[num],
+					Observed:      disagree,
+					QuorumReached: reached,
+				})
+				if err != nil {
+					slog.Error("Failed to record discrepancy", "block", num, "kind", kind, "error", err, "type", "db_fatal")
+					return fmt.Errorf("failed to record %s discrepancy for block %d: %w", kind, num, err)
+				}
+			}
+		}
+		if reached {
+			continue
+		}
+
+		if len(disagree) > 0 {
+			slog.Error("ALERT: Quorum mismatch", "block", num, "kind", kind, "agree", agree, "quorum", qf.quorum, "policy", qf.policy)
+			if qf.policy == MismatchHalt {
+				return fmt.Errorf("%w: %s of block %d", ErrQuorumMismatch, kind, num)
+			}
+			continue
+		}
+		slog.Warn("Quorum not reached, too few providers answered", "block", num, "kind", kind, "agree", agree, "quorum", qf.quorum, "policy", qf.policy)
+		if qf.policy == MismatchHalt {
+			return fmt.Errorf("%w: %s of block %d", ErrQuorumUnavailable, kind, num)
+		}
+	}
+	return nil
+}
+
+func blockHashes(blocks []BlockLogs) map[uint64]string {
+	hashes := make(map[uint64]string, len(blocks))
+	for _, b := range blocks {
+		hashes[b.Header.Number.Uint64()] = b.Header.Hash().String()
+	}
+	return hashes
+}
+
+func headerHashes(headers []*types.Header) map[uint64]string {
+	hashes := make(map[uint64]string, len(headers))
+	for _, h := range headers {
+		hashes[h.Number.Uint64()] = h.Hash().String()
+	}
+	return hashes
+}
+
+func blockLogCounts(blocks []BlockLogs) map[uint64]string {
+	counts := make(map[uint64]string, len(blocks))
+	for _, b := range blocks {
+		counts[b.Header.Number.Uint64()] = strconv.Itoa(len(b.Logs))
+	}
+	return counts
+}
+
+// logCounts returns the number of logs per block for every block in the range,
+// including blocks without logs.
+func logCounts(startBlock, endBlock uint64, logs []types.Log) map[uint64]string {
+	perBlock := make(map[uint64]int)
+	for _, log := range logs {
+		perBlock[log.BlockNumber]++
+	}
+	counts := make(map[uint64]string, endBlock-startBlock+1)
+	for num := startBlock; num <= endBlock; num++ {
+		counts[num] = strconv.Itoa(perBlock[num])
+	}
+	return counts
+}
@@ -0,0 +1,69 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestQuorumFetcherGetBlocksInRange(t *testing.T) {
+	primary := newTestNode(5)
+	honest := newTestNode(5)
+	liar := newTestNode(5)
+	liar.headers[3].Time++ // a different hash for block 3
+	liar.logs[2] = nil     // and a missing transfer in block 2
+
+	t.Run("agreement", func(t *testing.T) {
+		fetcher, err := NewQuorumFetcher(NewBlockFetcher(primary.dial(t)),
+			[]Endpoint{{Name: "honest", Client: honest.dial(t)}}, QuorumConfig{})
+		if err != nil {
+			t.Fatalf("Failed to create quorum fetcher: %v", err)
+		}
+		if _, err := fetcher.GetBlocksInRange(context.Background(), 1, 4); err != nil {
+			t.Fatalf("expected providers to agree, got %v", err)
+		}
+	})
+
+	t.Run("halt", func(t *testing.T) {
+		fetcher, err := NewQuorumFetcher(NewBlockFetcher(primary.dial(t)),
+			[]Endpoint{{Name: "liar", Client: liar.dial(t)}}, QuorumConfig{Quorum: 2})
+		if err != nil {
+			t.Fatalf("Failed to create quorum fetcher: %v", err)
+		}
+		if _, err := fetcher.GetBlocksInRange(context.Background(), 1, 4); !errors.Is(err, ErrQuorumMismatch) {
+			t.Fatalf("expected ErrQuorumMismatch, got %v", err)
+		}
+	})
+
+	t.Run("record", func(t *testing.T) {
+		var recorded []Discrepancy
+		fetcher, err := NewQuorumFetcher(NewBlockFetcher(primary.dial(t)),
+			[]Endpoint{{Name: "honest", Client: honest.dial(t)}, {Name: "liar", Client: liar.dial(t)}},
+			QuorumConfig{
+				Policy: MismatchRecord,
+				Recorder: DiscrepancyRecorderFunc(func(ctx context.Context, d Discrepancy) error {
+					recorded = append(recorded, d)
+					return nil
+				}),
+			})
+		if err != nil {
+			t.Fatalf("Failed to create quorum fetcher: %v", err)
+		}
+		blocks, err := fetcher.GetBlocksInRange(context.Background(), 1, 4)
+		if err != nil {
+			t.Fatalf("expected record mode to carry on, got %v", err)
+		}
+		if len(blocks) != 4 {
+			t.Fatalf("expected 4 blocks, got %d", len(blocks))
+		}
+		if len(recorded) != 2 {
+			t.Fatalf("expected 2 discrepancies, got %+v", recorded)
+		}
+		if d := recorded[0]; d.BlockNumber != 3 || d.Kind != DiscrepancyBlockHash || !d.QuorumReached || d.Observed["liar"] == "" {
+			t.Errorf("unexpected block hash discrepancy %+v", d)
+		}
+		if d := recorded[1]; d.BlockNumber != 2 || d.Kind != DiscrepancyLogCount || d.Expected != "1" || d.Observed["liar"] != "0" {
+			t.Errorf("unexpected log count discrepancy %+v", d)
+		}
+	})
+}
@@ -0,0 +1,140 @@
+package gateway
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/KhanSufiyanMirza/evm-indexer-go/internal/metrics"
+)
+
+// defaultMethodCost is charged for methods missing from the cost table.
+const defaultMethodCost = 20
+
+// DefaultMethodCosts are compute-unit costs per JSON-RPC method, modelled on
+// the pricing of the common hosted providers.
+var DefaultMethodCosts = map[string]int{
+	"eth_chainId":               0,
+	"eth_blockNumber":           10,
+	"eth_getBlockByNumber":      16,
+	"eth_getBlockByHash":        16,
+	"eth_getLogs":               75,
+	"eth_getTransactionReceipt": 15,
+	"eth_getBlockReceipts":      500,
+	"debug_traceBlockByHash":    500,
+}
+
+// RateLimit is the client-side budget applied to each RPC endpoint.
+// A zero rate disables that limit.
+type RateLimit struct {
+	RequestsPerSecond     float64
+	ComputeUnitsPerSecond float64
+	// MethodCosts overrides DefaultMethodCosts per method.
+	MethodCosts map[string]int
+}
+
+// WithRateLimit throttles every endpoint to the given budget. Each endpoint
+// gets its own token buckets, so adding providers adds capacity.
+func WithRateLimit(limit RateLimit) Option {
+	return func(bf *blockFetcher) {
+		bf.rateLimit = limit
+	}
+}
+
+// rateLimiter throttles calls to one endpoint by request count and compute units.
+type rateLimiter struct {
+	requests *tokenBucket
+	units    *tokenBucket
+	costs    map[string]int
+}
+
+func newRateLimiter(limit RateLimit) *rateLimiter {
+	costs := make(map[string]int, len(DefaultMethodCosts)+len(limit.MethodCosts))
+	for method, cost := range DefaultMethodCosts {
+		costs[method] = cost
+	}
+	for method, cost := range limit.MethodCosts {
+		costs[method] = cost
+	}
+	return &rateLimiter{
+		requests: newTokenBucket(limit.RequestsPerSecond),
+		units:    newTokenBucket(limit.ComputeUnitsPerSecond),
+		costs:    costs,
+	}
+}
+
+func (l *rateLimiter) cost(method string) int {
+	if cost, ok := l.costs[method]; ok {
+		return cost
+	}
+	return defaultMethodCost
+}
+
+// wait blocks until the endpoint has budget for one call of each of methods
+// (a batch passes one entry per element) and charges it.
+func (s *endpointState) wait(ctx context.Context, methods ...string) error {
+	if s.limiter == nil {
+		return nil
+	}
+	units := 0
+	for _, method := range methods {
+		units += s.limiter.cost(method)
+	}
+	metrics.RPCComputeUnitsTotal.WithLabelValues(s.Name).Add(float64(units))
+
+	delay := max(s.limiter.requests.reserve(float64(len(methods))), s.limiter.units.reserve(float64(units)))
+	if delay <= 0 {
+		return nil
+	}
+	metrics.RPCRateLimitWaitSeconds.WithLabelValues(s.Name).Add(delay.Seconds())
+	if waited, ok := ctx.Value(rateLimitWaitKey{}).(*atomic.Int64); ok {
+		waited.Add(int64(delay))
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// tokenBucket refills at rate tokens per second up to one second's worth.
+// A nil bucket (rate 0) never throttles.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64) *tokenBucket {
+	if rate <= 0 {
+		return nil
+	}
+	burst := max(rate, 1)
+	return &tokenBucket{rate: rate, burst: burst, tokens: burst, last: time.Now()}
+}
+
+// reserve takes n tokens and returns how long the caller has to wait before
+// using them. The bucket may go into debt so that calls costing more than the
+// burst still get through, and later callers queue up behind them.
+func (b *tokenBucket) reserve(n float64) time.Duration {
+	if b == nil || n <= 0 {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	b.tokens -= n
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
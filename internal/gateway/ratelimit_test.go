@@ -0,0 +1,42 @@
+package gateway
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTokenBucketReserve(t *testing.T) {
+	if d := (*tokenBucket)(nil).reserve(100); d != 0 {
+		t.Fatalf("expected unlimited bucket not to wait, got %s", d)
+	}
+
+	b := newTokenBucket(10)
+	// The first second's worth is available right away.
+	if d := b.reserve(10); d != 0 {
+		t.Fatalf("expected burst to be free, got %s", d)
+	}
+	// The next 5 tokens take half a second to refill.
+	if d := b.reserve(5); d < 400*time.Millisecond || d > 500*time.Millisecond {
+		t.Fatalf("expected ~500ms wait, got %s", d)
+	}
+}
+
+func TestEndpointWaitChargesMethodCosts(t *testing.T) {
+	pool := newEndpointPool([]Endpoint{{Name: "primary"}})
+	ep := pool.endpoints[0]
+	ep.limiter = newRateLimiter(RateLimit{
+		ComputeUnitsPerSecond: 100,
+		MethodCosts:           map[string]int{"eth_getLogs": 100},
+	})
+
+	if err := ep.wait(context.Background(), "eth_getLogs"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The budget is spent, so a cancelled caller gives up instead of waiting.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := ep.wait(ctx, "eth_getLogs"); err == nil {
+		t.Fatal("expected an error once the budget is spent and the context is cancelled")
+	}
+}
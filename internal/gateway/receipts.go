@@ -0,0 +1,103 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// GetBlockReceipts fetches the receipts of every transaction in the block with
+// the given hash, in transaction order. It uses eth_getBlockReceipts and falls
+// back to one eth_getTransactionReceipt per transaction on endpoints that do
+// not support it. If the node does not know the hash the error wraps
+// ErrUnknownBlockHash.
+func (bf *blockFetcher) GetBlockReceipts(ctx context.Context, blockHash common.Hash) (types.Receipts, error) {
+	receipts, err := callEndpoint(ctx, bf.pool, bf.tried(ctx), func(ctx context.Context, ep *endpointState) (types.Receipts, error) {
+		return ep.blockReceipts(ctx, blockHash, bf.batchSize)
+	})
+	if err != nil && ClassifyError(err) == ClassNodeLag {
+		return nil, fmt.Errorf("%w: %w", ErrUnknownBlockHash, err)
+	}
+	return receipts, err
+}
+
+// blockReceipts tries eth_getBlockReceipts unless the endpoint is known not
+// to support it, then falls back to per-transaction receipts.
+func (s *endpointState) blockReceipts(ctx context.Context, blockHash common.Hash, batchSize int) (types.Receipts, error) {
+	s.mu.Lock()
+	unsupported := s.noBlockReceipts
+	s.mu.Unlock()
+
+	if !unsupported {
+		if err := s.wait(ctx, "eth_getBlockReceipts"); err != nil {
+			return nil, err
+		}
+		receipts, err := s.Client.BlockReceipts(ctx, rpc.BlockNumberOrHashWithHash(blockHash, true))
+		if err == nil {
+			if len(receipts) > 0 && receipts[0].BlockHash != blockHash {
+				return nil, fmt.Errorf("receipts belong to block %s, expected %s", receipts[0].BlockHash, blockHash)
+			}
+			return receipts, nil
+		}
+		if !isMethodNotFound(err) {
+			return nil, err
+		}
+		slog.Warn("eth_getBlockReceipts not supported, falling back to eth_getTransactionReceipt", "endpoint", s.Name, "error", err)
+		s.mu.Lock()
+		s.noBlockReceipts = true
+		s.mu.Unlock()
+	}
+	return s.transactionReceipts(ctx, blockHash, batchSize)
+}
+
+// transactionReceipts fetches the block's transaction hashes and then their
+// receipts in batches of at most batchSize.
+func (s *endpointState) transactionReceipts(ctx context.Context, blockHash common.Hash, batchSize int) (types.Receipts, error) {
+	if err := s.wait(ctx, "eth_getBlockByHash"); err != nil {
+		return nil, err
+	}
+	var block *struct {
+		Transactions []common.Hash `json:"transactions"`
+	}
+	if err := s.Client.Client().CallContext(ctx, &block, "eth_getBlockByHash", blockHash, false); err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, errHeaderNotFound
+	}
+
+	receipts := make(types.Receipts, len(block.Transactions))
+	for from := 0; from < len(block.Transactions); from += batchSize {
+		to := min(from+batchSize, len(block.Transactions))
+		elems := make([]rpc.BatchElem, 0, to-from)
+		methods := make([]string, 0, to-from)
+		for i := from; i < to; i++ {
+			elems = append(elems, rpc.BatchElem{
+				Method: "eth_getTransactionReceipt",
+				Args:   []any{block.Transactions[i]},
+				Result: &receipts[i],
+			})
+			methods = append(methods, "eth_getTransactionReceipt")
+		}
+		if err := s.wait(ctx, methods...); err != nil {
+			return nil, err
+		}
+		if err := s.Client.Client().BatchCallContext(ctx, elems); err != nil {
+			return nil, err
+		}
+		for i, elem := range elems {
+			if elem.Error != nil {
+				return nil, fmt.Errorf("receipt of %s: %w", block.Transactions[from+i], elem.Error)
+			}
+			if receipts[from+i] == nil || receipts[from+i].BlockHash != blockHash {
+				// Not mined yet on this node, or mined in another block after a reorg.
+				return nil, fmt.Errorf("receipt of %s: %w", block.Transactions[from+i], errHeaderNotFound)
+			}
+		}
+	}
+	return receipts, nil
+}
@@ -0,0 +1,44 @@
+package gateway
+
+import (
+	"context"
+	"testing"
+)
+
+func TestGetBlockReceipts(t *testing.T) {
+	for _, tc := range []struct {
+		name            string
+		noBlockReceipts bool
+	}{
+		{name: "eth_getBlockReceipts"},
+		{name: "fallback to eth_getTransactionReceipt", noBlockReceipts: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			node := newTestNode(3)
+			node.noBlockReceipts = tc.noBlockReceipts
+			fetcher := NewBlockFetcher(node.dial(t))
+			hash := node.headers[2].Hash()
+
+			for range 2 {
+				receipts, err := fetcher.GetBlockReceipts(context.Background(), hash)
+				if err != nil {
+					t.Fatalf("Failed to get receipts: %v", err)
+				}
+				if len(receipts) != 1 || receipts[0].TxHash != node.logs[2][0].TxHash || receipts[0].BlockHash != hash {
+					t.Fatalf("unexpected receipts %+v", receipts)
+				}
+			}
+			// An unsupported eth_getBlockReceipts is only tried once per endpoint.
+			wantBlockReceipts := 2
+			if tc.noBlockReceipts {
+				wantBlockReceipts = 1
+			}
+			if got := node.callCount("eth_getBlockReceipts"); got != wantBlockReceipts {
+				t.Errorf("expected %d eth_getBlockReceipts calls, got %d", wantBlockReceipts, got)
+			}
+			if got := node.callCount("eth_getTransactionReceipt"); tc.noBlockReceipts && got != 2 {
+				t.Errorf("expected 2 eth_getTransactionReceipt calls, got %d", got)
+			}
+		})
+	}
+}
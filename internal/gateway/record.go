@@ -0,0 +1,282 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Fixture layout: one block-<number>.json per block, one receipts-<hash>.json
+// and traces-<hash>.json per block whose receipts or internal transfers were
+// fetched, plus head.json holding the chain head
+// returned by GetBlockNumber and the safe and finalized blocks
+// returned by GetTaggedBlockNumber.
+const headFixture = "head.json"
+
+// blockFixture is everything recorded about one block: AllLogs as returned by
+// GetLogsInRange, Logs matching the fetcher's log filter as returned by
+// GetERC20TransfersInRange and GetBlocksInRange. A nil AllLogs or Logs slice
+// (JSON null) means it was never fetched, an empty one means the block had
+// none.
+type blockFixture struct {
+	Number  uint64        `json:"number"`
+	Header  *types.Header `json:"header"`
+	Block   hexutil.Bytes `json:"block,omitempty"` // RLP of the full block, only recorded by Fetch
+	AllLogs []types.Log   `json:"allLogs"`
+	Logs    []types.Log   `json:"logs"`
+}
+
+type headFixtureFile struct {
+	BlockNumber uint64  `json:"blockNumber"`
+	Safe        *uint64 `json:"safe,omitempty"`
+	Finalized   *uint64 `json:"finalized,omitempty"`
+}
+
+func blockFixtureName(number uint64) string {
+	return fmt.Sprintf("block-%d.json", number)
+}
+
+func receiptsFixtureName(blockHash common.Hash) string {
+	return fmt.Sprintf("receipts-%s.json", blockHash.Hex())
+}
+
+func tracesFixtureName(blockHash common.Hash) string {
+	return fmt.Sprintf("traces-%s.json", blockHash.Hex())
+}
+
+// recordingFetcher passes every call through to the wrapped fetcher and
+// writes what it returned to fixture files that NewReplayFetcher serves.
+// Methods it does not override are passed through unrecorded.
+type recordingFetcher struct {
+	BlockFetcher
+	dir string
+	mu  sync.Mutex
+}
+
+// NewRecordingFetcher wraps inner so that blocks, headers and logs it returns
+// are saved as JSON fixtures in dir. Recording a block again merges into its
+// existing fixture, so a fixture set can be built up over several runs.
+func NewRecordingFetcher(inner BlockFetcher, dir string) (BlockFetcher, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create fixture dir: %w", err)
+	}
+	return &recordingFetcher{BlockFetcher: inner, dir: dir}, nil
+}
+
+func (rf *recordingFetcher) Fetch(ctx context.Context, blockNumber uint64) (*types.Block, error) {
+	block, err := rf.BlockFetcher.Fetch(ctx, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := rlp.EncodeToBytes(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode block %d for recording: %w", blockNumber, err)
+	}
+	err = rf.update(blockNumber, func(f *blockFixture) {
+		f.Header = block.Header()
+		f.Block = encoded
+	})
+	if err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+func (rf *recordingFetcher) FetchHeader(ctx context.Context, blockNumber uint64) (*types.Header, error) {
+	header, err := rf.BlockFetcher.FetchHeader(ctx, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	if err := rf.update(blockNumber, func(f *blockFixture) { f.Header = header }); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
+
+func (rf *recordingFetcher) GetBlockNumber(ctx context.Context) (uint64, error) {
+	blockNumber, err := rf.BlockFetcher.GetBlockNumber(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if err := rf.updateHead(func(f *headFixtureFile) { f.BlockNumber = blockNumber }); err != nil {
+		return 0, err
+	}
+	return blockNumber, nil
+}
+
+func (rf *recordingFetcher) GetTaggedBlockNumber(ctx context.Context, tag rpc.BlockNumber) (uint64, error) {
+	blockNumber, err := rf.BlockFetcher.GetTaggedBlockNumber(ctx, tag)
+	if err != nil {
+		return 0, err
+	}
+	err = rf.updateHead(func(f *headFixtureFile) {
+		switch tag {
+		case rpc.SafeBlockNumber:
+			f.Safe = &blockNumber
+		case rpc.FinalizedBlockNumber:
+			f.Finalized = &blockNumber
+		}
+	})
+	if err != nil {
+		return 0, err
+	}
+	return blockNumber, nil
+}
+
+func (rf *recordingFetcher) GetLogsInRange(ctx context.Context, startBlock, endBlock uint64) ([]types.Log, error) {
+	logs, err := rf.BlockFetcher.GetLogsInRange(ctx, startBlock, endBlock)
+	if err != nil {
+		return nil, err
+	}
+	perBlock := groupLogs(startBlock, endBlock, logs)
+	for num := startBlock; num <= endBlock; num++ {
+		if err := rf.update(num, func(f *blockFixture) { f.AllLogs = perBlock[num] }); err != nil {
+			return nil, err
+		}
+	}
+	return logs, nil
+}
+
+func (rf *recordingFetcher) GetERC20TransfersInRange(ctx context.Context, startBlock, endBlock uint64) ([]types.Log, error) {
+	logs, err := rf.BlockFetcher.GetERC20TransfersInRange(ctx, startBlock, endBlock)
+	if err != nil {
+		return nil, err
+	}
+	perBlock := groupLogs(startBlock, endBlock, logs)
+	for num := startBlock; num <= endBlock; num++ {
+		if err := rf.update(num, func(f *blockFixture) { f.Logs = perBlock[num] }); err != nil {
+			return nil, err
+		}
+	}
+	return logs, nil
+}
+
+func (rf *recordingFetcher) GetBlocksInRange(ctx context.Context, startBlock, endBlock uint64) ([]BlockLogs, error) {
+	blocks, err := rf.BlockFetcher.GetBlocksInRange(ctx, startBlock, endBlock)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range blocks {
+		err := rf.update(b.Header.Number.Uint64(), func(f *blockFixture) {
+			f.Header = b.Header
+			f.Logs = append([]types.Log{}, b.Logs...)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return blocks, nil
+}
+
+func (rf *recordingFetcher) GetHeadersInRange(ctx context.Context, startBlock, endBlock uint64) ([]*types.Header, error) {
+	headers, err := rf.BlockFetcher.GetHeadersInRange(ctx, startBlock, endBlock)
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range headers {
+		if err := rf.update(h.Number.Uint64(), func(f *blockFixture) { f.Header = h }); err != nil {
+			return nil, err
+		}
+	}
+	return headers, nil
+}
+
+func (rf *recordingFetcher) GetBlockReceipts(ctx context.Context, blockHash common.Hash) (types.Receipts, error) {
+	receipts, err := rf.BlockFetcher.GetBlockReceipts(ctx, blockHash)
+	if err != nil {
+		return nil, err
+	}
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if err := writeFixture(filepath.Join(rf.dir, receiptsFixtureName(blockHash)), receipts); err != nil {
+		return nil, err
+	}
+	return receipts, nil
+}
+
+func (rf *recordingFetcher) GetInternalTransfers(ctx context.Context, blockHash common.Hash) ([]InternalTransfer, error) {
+	transfers, err := rf.BlockFetcher.GetInternalTransfers(ctx, blockHash)
+	if err != nil {
+		return nil, err
+	}
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if err := writeFixture(filepath.Join(rf.dir, tracesFixtureName(blockHash)), transfers); err != nil {
+		return nil, err
+	}
+	return transfers, nil
+}
+
+// update applies fn to the fixture of block number and writes it back.
+func (rf *recordingFetcher) update(number uint64, fn func(*blockFixture)) error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	path := filepath.Join(rf.dir, blockFixtureName(number))
+	fixture := blockFixture{Number: number}
+	if err := readFixture(path, &fixture); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	fn(&fixture)
+	return writeFixture(path, fixture)
+}
+
+// updateHead applies fn to the head fixture and writes it back.
+func (rf *recordingFetcher) updateHead(fn func(*headFixtureFile)) error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	path := filepath.Join(rf.dir, headFixture)
+	var head headFixtureFile
+	if err := readFixture(path, &head); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	fn(&head)
+	return writeFixture(path, head)
+}
+
+// groupLogs splits logs by block, with an empty (non-nil) entry for every
+// block in the range that had none.
+func groupLogs(startBlock, endBlock uint64, logs []types.Log) map[uint64][]types.Log {
+	perBlock := make(map[uint64][]types.Log, endBlock-startBlock+1)
+	for num := startBlock; num <= endBlock; num++ {
+		perBlock[num] = []types.Log{}
+	}
+	for _, log := range logs {
+		perBlock[log.BlockNumber] = append(perBlock[log.BlockNumber], log)
+	}
+	return perBlock
+}
+
+func readFixture(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to decode fixture %s: %w", path, err)
+	}
+	return nil
+}
+
+func writeFixture(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode fixture %s: %w", path, err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write fixture %s: %w", path, err)
+	}
+	return nil
+}
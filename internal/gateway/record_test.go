@@ -0,0 +1,142 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestRecordReplay(t *testing.T) {
+	ctx := context.Background()
+	node := newTestNode(6)
+	dir := t.TempDir()
+
+	recorder, err := NewRecordingFetcher(NewBlockFetcher(node.dial(t)), dir)
+	if err != nil {
+		t.Fatalf("Failed to create recording fetcher: %v", err)
+	}
+	head, err := recorder.GetBlockNumber(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get block number: %v", err)
+	}
+	block, err := recorder.Fetch(ctx, 2)
+	if err != nil {
+		t.Fatalf("Failed to fetch block: %v", err)
+	}
+	blocks, err := recorder.GetBlocksInRange(ctx, 1, 3)
+	if err != nil {
+		t.Fatalf("Failed to get blocks: %v", err)
+	}
+	logs, err := recorder.GetLogsInRange(ctx, 4, 5)
+	if err != nil {
+		t.Fatalf("Failed to get logs: %v", err)
+	}
+	receipts, err := recorder.GetBlockReceipts(ctx, block.Hash())
+	if err != nil {
+		t.Fatalf("Failed to get receipts: %v", err)
+	}
+
+	replay, err := NewReplayFetcher(dir)
+	if err != nil {
+		t.Fatalf("Failed to load fixtures: %v", err)
+	}
+	if got, _ := replay.GetBlockNumber(ctx); got != head {
+		t.Errorf("expected head %d, got %d", head, got)
+	}
+	replayedBlock, err := replay.Fetch(ctx, 2)
+	if err != nil {
+		t.Fatalf("Failed to replay block: %v", err)
+	}
+	if replayedBlock.Hash() != block.Hash() {
+		t.Errorf("expected block hash %s, got %s", block.Hash(), replayedBlock.Hash())
+	}
+	replayedBlocks, err := replay.GetBlocksInRange(ctx, 1, 3)
+	if err != nil {
+		t.Fatalf("Failed to replay blocks: %v", err)
+	}
+	for i := range blocks {
+		if replayedBlocks[i].Header.Hash() != blocks[i].Header.Hash() || !reflect.DeepEqual(replayedBlocks[i].Logs, blocks[i].Logs) {
+			t.Errorf("block %d differs after replay", blocks[i].Header.Number)
+		}
+	}
+	replayedLogs, err := replay.GetLogsInRange(ctx, 4, 5)
+	if err != nil {
+		t.Fatalf("Failed to replay logs: %v", err)
+	}
+	if !reflect.DeepEqual(replayedLogs, logs) {
+		t.Errorf("expected logs %+v, got %+v", logs, replayedLogs)
+	}
+	replayedReceipts, err := replay.GetBlockReceipts(ctx, block.Hash())
+	if err != nil {
+		t.Fatalf("Failed to replay receipts: %v", err)
+	}
+	if len(replayedReceipts) != len(receipts) || replayedReceipts[0].TxHash != receipts[0].TxHash {
+		t.Errorf("expected receipts %+v, got %+v", receipts, replayedReceipts)
+	}
+	// Transfers of blocks only recorded via GetLogsInRange are filtered from the full log set.
+	if transfers, err := replay.GetERC20TransfersInRange(ctx, 4, 5); err != nil || len(transfers) != 2 {
+		t.Errorf("expected 2 transfers, got %d (%v)", len(transfers), err)
+	}
+
+	if _, err := replay.GetLogsInRange(ctx, 1, 1); !errors.Is(err, ErrFixtureNotFound) {
+		t.Errorf("expected ErrFixtureNotFound for unrecorded logs, got %v", err)
+	}
+	if _, err := replay.Fetch(ctx, 42); !errors.Is(err, ErrFixtureNotFound) {
+		t.Errorf("expected ErrFixtureNotFound for unrecorded block, got %v", err)
+	}
+}
+
+func TestReplayReorg(t *testing.T) {
+	ctx := context.Background()
+	canonical := newTestNode(5)
+	fork := newTestNode(5)
+	fork.headers[3].Time++ // block 3 is replaced by a sibling
+	fork.headers[4].ParentHash = fork.headers[3].Hash()
+	fork.logs[3] = append(fork.logs[3], types.Log{Address: common.HexToAddress("0xbb"), Topics: []common.Hash{erc20TransferEventHash, {}, {}}, BlockNumber: 3, Index: 1})
+
+	canonicalDir, forkDir := t.TempDir(), t.TempDir()
+	record := func(node *testNode, dir string, start, end uint64) {
+		recorder, err := NewRecordingFetcher(NewBlockFetcher(node.dial(t)), dir)
+		if err != nil {
+			t.Fatalf("Failed to create recording fetcher: %v", err)
+		}
+		if _, err := recorder.GetBlocksInRange(ctx, start, end); err != nil {
+			t.Fatalf("Failed to record blocks: %v", err)
+		}
+	}
+	record(canonical, canonicalDir, 0, 4)
+	record(fork, forkDir, 3, 4)
+
+	replay, err := NewReplayFetcher(canonicalDir)
+	if err != nil {
+		t.Fatalf("Failed to load fixtures: %v", err)
+	}
+	before, err := replay.GetBlocksInRange(ctx, 2, 4)
+	if err != nil {
+		t.Fatalf("Failed to replay blocks: %v", err)
+	}
+	if err := replay.Load(forkDir); err != nil {
+		t.Fatalf("Failed to load fork: %v", err)
+	}
+	after, err := replay.GetBlocksInRange(ctx, 2, 4)
+	if err != nil {
+		t.Fatalf("Failed to replay blocks: %v", err)
+	}
+
+	if before[0].Header.Hash() != after[0].Header.Hash() {
+		t.Errorf("expected block 2 to survive the reorg")
+	}
+	if before[1].Header.Hash() == after[1].Header.Hash() {
+		t.Errorf("expected block 3 to be replaced by the fork")
+	}
+	if after[2].Header.ParentHash != after[1].Header.Hash() {
+		t.Errorf("expected block 4 to build on the fork")
+	}
+	if len(after[1].Logs) != 2 {
+		t.Errorf("expected the fork's 2 logs in block 3, got %d", len(after[1].Logs))
+	}
+}
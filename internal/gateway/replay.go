@@ -0,0 +1,265 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// ErrFixtureNotFound is returned by a ReplayFetcher asked for data that was
+// never recorded.
+var ErrFixtureNotFound = errors.New("no recorded fixture")
+
+// ReplayFetcher is a BlockFetcher serving fixtures written by
+// NewRecordingFetcher, without any network access.
+type ReplayFetcher struct {
+	mu       sync.RWMutex
+	blocks   map[uint64]*blockFixture
+	receipts map[common.Hash]types.Receipts
+	traces   map[common.Hash][]InternalTransfer
+	head     uint64
+	// safe and finalized are nil unless recorded, like on a chain without tags.
+	safe      *uint64
+	finalized *uint64
+}
+
+var _ BlockFetcher = (*ReplayFetcher)(nil)
+
+// NewReplayFetcher loads the fixtures in each of dirs, see Load.
+func NewReplayFetcher(dirs ...string) (*ReplayFetcher, error) {
+	rf := &ReplayFetcher{
+		blocks:   make(map[uint64]*blockFixture),
+		receipts: make(map[common.Hash]types.Receipts),
+		traces:   make(map[common.Hash][]InternalTransfer),
+	}
+	for _, dir := range dirs {
+		if err := rf.Load(dir); err != nil {
+			return nil, err
+		}
+	}
+	return rf, nil
+}
+
+// Load adds the fixtures in dir, replacing already loaded blocks with the
+// same number. Loading the fixtures of a fork on top of the canonical chain
+// replays a reorg. The head moves to the recorded head of dir, or else to the
+// highest block loaded so far.
+func (rf *ReplayFetcher) Load(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read fixture dir: %w", err)
+	}
+
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	var head *headFixtureFile
+	for _, entry := range entries {
+		name := entry.Name()
+		path := filepath.Join(dir, name)
+		switch {
+		case name == headFixture:
+			head = new(headFixtureFile)
+			if err := readFixture(path, head); err != nil {
+				return err
+			}
+		case strings.HasPrefix(name, "block-") && strings.HasSuffix(name, ".json"):
+			fixture := new(blockFixture)
+			if err := readFixture(path, fixture); err != nil {
+				return err
+			}
+			rf.blocks[fixture.Number] = fixture
+		case strings.HasPrefix(name, "receipts-") && strings.HasSuffix(name, ".json"):
+			var receipts types.Receipts
+			if err := readFixture(path, &receipts); err != nil {
+				return err
+			}
+			hash := common.HexToHash(strings.TrimSuffix(strings.TrimPrefix(name, "receipts-"), ".json"))
+			rf.receipts[hash] = receipts
+		case strings.HasPrefix(name, "traces-") && strings.HasSuffix(name, ".json"):
+			transfers := []InternalTransfer{}
+			if err := readFixture(path, &transfers); err != nil {
+				return err
+			}
+			hash := common.HexToHash(strings.TrimSuffix(strings.TrimPrefix(name, "traces-"), ".json"))
+			rf.traces[hash] = transfers
+		}
+	}
+
+	if head != nil {
+		rf.head = head.BlockNumber
+		rf.safe, rf.finalized = head.Safe, head.Finalized
+		return nil
+	}
+	for num := range rf.blocks {
+		rf.head = max(rf.head, num)
+	}
+	return nil
+}
+
+func (rf *ReplayFetcher) Fetch(ctx context.Context, blockNumber uint64) (*types.Block, error) {
+	rf.mu.RLock()
+	defer rf.mu.RUnlock()
+	fixture, ok := rf.blocks[blockNumber]
+	switch {
+	case !ok || fixture.Header == nil:
+		return nil, fmt.Errorf("%w: block %d", ErrFixtureNotFound, blockNumber)
+	case len(fixture.Block) == 0:
+		// Only the header was recorded, which is all the indexer looks at.
+		return types.NewBlockWithHeader(fixture.Header), nil
+	}
+	block := new(types.Block)
+	if err := rlp.DecodeBytes(fixture.Block, block); err != nil {
+		return nil, fmt.Errorf("failed to decode recorded block %d: %w", blockNumber, err)
+	}
+	return block, nil
+}
+
+func (rf *ReplayFetcher) FetchHeader(ctx context.Context, blockNumber uint64) (*types.Header, error) {
+	rf.mu.RLock()
+	defer rf.mu.RUnlock()
+	fixture, ok := rf.blocks[blockNumber]
+	if !ok || fixture.Header == nil {
+		return nil, fmt.Errorf("%w: header of block %d", ErrFixtureNotFound, blockNumber)
+	}
+	return types.CopyHeader(fixture.Header), nil
+}
+
+func (rf *ReplayFetcher) GetBlockNumber(ctx context.Context) (uint64, error) {
+	rf.mu.RLock()
+	defer rf.mu.RUnlock()
+	if len(rf.blocks) == 0 {
+		return 0, fmt.Errorf("%w: chain head", ErrFixtureNotFound)
+	}
+	return rf.head, nil
+}
+
+// GetTaggedBlockNumber returns the recorded safe or finalized block. A tag
+// that was never recorded is unsupported, as on a chain without finality.
+func (rf *ReplayFetcher) GetTaggedBlockNumber(ctx context.Context, tag rpc.BlockNumber) (uint64, error) {
+	rf.mu.RLock()
+	defer rf.mu.RUnlock()
+	var blockNumber *uint64
+	switch tag {
+	case rpc.LatestBlockNumber:
+		return rf.head, nil
+	case rpc.SafeBlockNumber:
+		blockNumber = rf.safe
+	case rpc.FinalizedBlockNumber:
+		blockNumber = rf.finalized
+	}
+	if blockNumber == nil {
+		return 0, fmt.Errorf("%w: %w: %s block", ErrFixtureNotFound, ErrBlockTagUnsupported, tag)
+	}
+	return *blockNumber, nil
+}
+
+func (rf *ReplayFetcher) GetLogsInRange(ctx context.Context, startBlock, endBlock uint64) ([]types.Log, error) {
+	rf.mu.RLock()
+	defer rf.mu.RUnlock()
+	var logs []types.Log
+	for num := startBlock; num <= endBlock; num++ {
+		fixture, ok := rf.blocks[num]
+		if !ok || fixture.AllLogs == nil {
+			return nil, fmt.Errorf("%w: logs of block %d", ErrFixtureNotFound, num)
+		}
+		logs = append(logs, fixture.AllLogs...)
+	}
+	return logs, nil
+}
+
+func (rf *ReplayFetcher) GetERC20TransfersInRange(ctx context.Context, startBlock, endBlock uint64) ([]types.Log, error) {
+	rf.mu.RLock()
+	defer rf.mu.RUnlock()
+	var logs []types.Log
+	for num := startBlock; num <= endBlock; num++ {
+		filtered, err := rf.logs(num)
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, filtered...)
+	}
+	return logs, nil
+}
+
+func (rf *ReplayFetcher) GetBlocksInRange(ctx context.Context, startBlock, endBlock uint64) ([]BlockLogs, error) {
+	rf.mu.RLock()
+	defer rf.mu.RUnlock()
+	blocks := make([]BlockLogs, 0, endBlock-startBlock+1)
+	for num := startBlock; num <= endBlock; num++ {
+		fixture, ok := rf.blocks[num]
+		if !ok || fixture.Header == nil {
+			return nil, fmt.Errorf("%w: header of block %d", ErrFixtureNotFound, num)
+		}
+		logs, err := rf.logs(num)
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, BlockLogs{Header: types.CopyHeader(fixture.Header), Logs: logs})
+	}
+	return blocks, nil
+}
+
+func (rf *ReplayFetcher) GetHeadersInRange(ctx context.Context, startBlock, endBlock uint64) ([]*types.Header, error) {
+	rf.mu.RLock()
+	defer rf.mu.RUnlock()
+	headers := make([]*types.Header, 0, endBlock-startBlock+1)
+	for num := startBlock; num <= endBlock; num++ {
+		fixture, ok := rf.blocks[num]
+		if !ok || fixture.Header == nil {
+			return nil, fmt.Errorf("%w: header of block %d", ErrFixtureNotFound, num)
+		}
+		headers = append(headers, types.CopyHeader(fixture.Header))
+	}
+	return headers, nil
+}
+
+func (rf *ReplayFetcher) GetBlockReceipts(ctx context.Context, blockHash common.Hash) (types.Receipts, error) {
+	rf.mu.RLock()
+	defer rf.mu.RUnlock()
+	receipts, ok := rf.receipts[blockHash]
+	if !ok {
+		return nil, fmt.Errorf("%w: receipts of block %s", ErrFixtureNotFound, blockHash)
+	}
+	return slices.Clone(receipts), nil
+}
+
+func (rf *ReplayFetcher) GetInternalTransfers(ctx context.Context, blockHash common.Hash) ([]InternalTransfer, error) {
+	rf.mu.RLock()
+	defer rf.mu.RUnlock()
+	transfers, ok := rf.traces[blockHash]
+	if !ok {
+		return nil, fmt.Errorf("%w: internal transfers of block %s", ErrFixtureNotFound, blockHash)
+	}
+	return slices.Clone(transfers), nil
+}
+
+// logs returns the recorded filtered logs of a block, falling back to the
+// Transfer logs of its full log set, the default filter. Callers must hold
+// rf.mu.
+func (rf *ReplayFetcher) logs(number uint64) ([]types.Log, error) {
+	fixture, ok := rf.blocks[number]
+	switch {
+	case ok && fixture.Logs != nil:
+		return slices.Clone(fixture.Logs), nil
+	case ok && fixture.AllLogs != nil:
+		var transfers []types.Log
+		for _, log := range fixture.AllLogs {
+			if len(log.Topics) > 0 && log.Topics[0] == erc20TransferEventHash {
+				transfers = append(transfers, log)
+			}
+		}
+		return transfers, nil
+	default:
+		return nil, fmt.Errorf("%w: filtered logs of block %d", ErrFixtureNotFound, number)
+	}
+}
@@ -0,0 +1,97 @@
+package gateway
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/KhanSufiyanMirza/evm-indexer-go/internal/metrics"
+	"github.com/cenkalti/backoff/v5"
+)
+
+// RetryPolicy configures the Retry interceptor. Zero fields take the value
+// of DefaultRetryPolicy.
+type RetryPolicy struct {
+	MaxTries        uint
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+}
+
+// DefaultRetryPolicy retries up to 5 times with exponential backoff.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxTries:        5,
+	InitialInterval: backoff.DefaultInitialInterval,
+	MaxInterval:     backoff.DefaultMaxInterval,
+}
+
+func (p RetryPolicy) options() []backoff.RetryOption {
+	if p.MaxTries == 0 {
+		p.MaxTries = DefaultRetryPolicy.MaxTries
+	}
+	b := backoff.NewExponentialBackOff()
+	if p.InitialInterval > 0 {
+		b.InitialInterval = p.InitialInterval
+	}
+	if p.MaxInterval > 0 {
+		b.MaxInterval = p.MaxInterval
+	}
+	return []backoff.RetryOption{backoff.WithBackOff(b), backoff.WithMaxTries(p.MaxTries)}
+}
+
+// Retry retries calls failing with a retryable ErrorClass. Every attempt is
+// routed to an endpoint the call has not tried yet, and calls made of several
+// requests (batches, log chunks) only redo the requests that failed.
+func Retry(policy RetryPolicy) Interceptor {
+	opts := policy.options()
+	return func(ctx context.Context, call Call, next Handler) (any, error) {
+		ctx = withRetryScope(ctx)
+		attempt := 1
+		return backoff.Retry(ctx, func() (any, error) {
+			res, err := next(ctx)
+			if err != nil {
+				class := ClassifyError(err)
+				metrics.RPCErrorsTotal.WithLabelValues(string(class)).Inc()
+				if !class.Retryable() {
+					slog.Error("Non-retryable RPC error", "method", call.Method, "args", call.Args, "error", err, "class", class, "type", "rpc_fatal")
+					return nil, backoff.Permanent(err)
+				}
+				slog.Warn("Retryable RPC error", "method", call.Method, "args", call.Args, "attempt", attempt, "error", err, "class", class, "type", "rpc_retry")
+			}
+			attempt++
+			return res, err
+		}, opts...)
+	}
+}
+
+// retryScope holds state shared by the attempts of one retried call, such as
+// the endpoints already tried or the part of a range already fetched.
+type retryScope struct {
+	mu     sync.Mutex
+	values map[any]any
+}
+
+type retryScopeKey struct{}
+
+func withRetryScope(ctx context.Context) context.Context {
+	return context.WithValue(ctx, retryScopeKey{}, &retryScope{values: make(map[any]any)})
+}
+
+// scopeValue returns the value stored under key by an earlier attempt of the
+// current call, creating it with newValue on first use. Without a retry scope
+// every attempt starts afresh. Keys must be distinct per fetcher, because a
+// nested fetcher without its own Retry sees the outer call's scope.
+func scopeValue[T any](ctx context.Context, key any, newValue func() T) T {
+	scope, ok := ctx.Value(retryScopeKey{}).(*retryScope)
+	if !ok {
+		return newValue()
+	}
+	scope.mu.Lock()
+	defer scope.mu.Unlock()
+	if v, ok := scope.values[key].(T); ok {
+		return v
+	}
+	v := newValue()
+	scope.values[key] = v
+	return v
+}
@@ -2,18 +2,17 @@ package gateway
 
 import (
 	"context"
-	"log/slog"
+	"errors"
+	"fmt"
 	"math/big"
-	"strings"
-	"time"
 
 	"github.com/KhanSufiyanMirza/evm-indexer-go/internal/metrics"
-	"github.com/cenkalti/backoff/v5"
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
 )
 
 func init() {
@@ -25,143 +24,188 @@ var erc20TransferEventHash common.Hash
 
 type BlockFetcher interface {
 	Fetch(ctx context.Context, blockNumber uint64) (*types.Block, error)
-	GetBlockNumberWithRetry(ctx context.Context) (uint64, error)
+	FetchHeader(ctx context.Context, blockNumber uint64) (*types.Header, error)
+	GetBlockNumber(ctx context.Context) (uint64, error)
+	GetTaggedBlockNumber(ctx context.Context, tag rpc.BlockNumber) (uint64, error)
 	GetLogsInRange(ctx context.Context, startBlock, endBlock uint64) ([]types.Log, error)
 	GetERC20TransfersInRange(ctx context.Context, startBlock, endBlock uint64) ([]types.Log, error)
+	GetBlocksInRange(ctx context.Context, startBlock, endBlock uint64) ([]BlockLogs, error)
+	GetHeadersInRange(ctx context.Context, startBlock, endBlock uint64) ([]*types.Header, error)
+	GetBlockReceipts(ctx context.Context, blockHash common.Hash) (types.Receipts, error)
+	GetInternalTransfers(ctx context.Context, blockHash common.Hash) ([]InternalTransfer, error)
 }
+
+// blockFetcher talks to the endpoint pool directly. Each of its methods makes
+// a single attempt (failing over within the pool only across attempts), so it
+// is always used wrapped in its interceptors, see Intercept.
 type blockFetcher struct {
-	client *ethclient.Client
+	pool         *endpointPool
+	batchSize    int
+	rateLimit    RateLimit
+	logFilters   []LogFilter
+	interceptors []Interceptor
 }
 
-// BlockFetcher is a function that fetches a block by its number.
-// type BlockFetcher func(ctx context.Context, blockNumber uint64) (*types.Block, error)
+// Option configures a BlockFetcher.
+type Option func(*blockFetcher)
 
-// var retryableCodes = map[int]bool{
-// 	-32001: true, // resource not found (node lag)
-// 	-32002: true, // resource unavailable
-// 	-32005: true, // rate limit
-// 	-32603: true, // internal error
-// 	-32016: true, // over rate limit
-// }
+// WithBatchSize sets how many blocks GetBlocksInRange and GetHeadersInRange
+// fetch per JSON-RPC batch request. Values below 1 are ignored.
+func WithBatchSize(n int) Option {
+	return func(bf *blockFetcher) {
+		if n > 0 {
+			bf.batchSize = n
+		}
+	}
+}
+
+// WithLogFilters sets the logs GetBlocksInRange and GetERC20TransfersInRange
+// fetch, those matching any of filters, ERC20 Transfer logs by default. Every
+// filter is one eth_getLogs query per block or range, the queries of a block
+// going out in the same batch request; to serve several event handlers with
+// as few queries as possible pass their filters merged with MergeLogFilters.
+// Without filters the default is kept.
+func WithLogFilters(filters ...LogFilter) Option {
+	return func(bf *blockFetcher) {
+		if len(filters) > 0 {
+			bf.logFilters = filters
+		}
+	}
+}
 
 // NewBlockFetcher returns a BlockFetcher that fetches blocks using the provided ethclient.Client.
-func NewBlockFetcher(client *ethclient.Client) BlockFetcher {
-	return &blockFetcher{client: client}
+func NewBlockFetcher(client *ethclient.Client, opts ...Option) BlockFetcher {
+	return newBlockFetcher([]Endpoint{{Name: "default", Client: client}}, opts).intercepted()
 }
 
-func (bf *blockFetcher) Fetch(ctx context.Context, blockNumber uint64) (*types.Block, error) {
-	st := time.Now()
-	defer func() {
-		slog.Info("Block fetched", "block", blockNumber, "duration", time.Since(st))
-	}()
-	count := 1
-	block, err := backoff.Retry(ctx, func() (*types.Block, error) {
-		slog.Info("Fetching block", "block", blockNumber, "attempt", count)
-		block, err := bf.client.BlockByNumber(ctx, big.NewInt(int64(blockNumber)))
+// NewMultiBlockFetcher returns a BlockFetcher that routes every call to the healthiest
+// of the given endpoints and fails over to the next one when a call errors.
+// The order of endpoints is the order of preference while they are equally healthy.
+func NewMultiBlockFetcher(endpoints []Endpoint, opts ...Option) (BlockFetcher, error) {
+	if len(endpoints) == 0 {
+		return nil, errors.New("at least one RPC endpoint is required")
+	}
+	return newBlockFetcher(endpoints, opts).intercepted(), nil
+}
 
-		if err != nil {
-			if !isRetryableError(err) {
-				slog.Error("Non-retryable RPC error", "error", err, "type", "rpc_fatal")
-				metrics.RPCErrorsTotal.WithLabelValues("fatal").Inc()
-				return nil, backoff.Permanent(err)
-			}
-			slog.Warn("Retryable RPC error", "error", err, "type", "rpc_retry")
-			metrics.RPCErrorsTotal.WithLabelValues("retryable").Inc()
+func newBlockFetcher(endpoints []Endpoint, opts []Option) *blockFetcher {
+	bf := &blockFetcher{
+		batchSize:    DefaultBatchSize,
+		logFilters:   []LogFilter{ERC20TransferFilter()},
+		interceptors: DefaultInterceptors(),
+	}
+	for _, opt := range opts {
+		opt(bf)
+	}
+	bf.pool = newEndpointPool(endpoints)
+	if bf.rateLimit.RequestsPerSecond > 0 || bf.rateLimit.ComputeUnitsPerSecond > 0 {
+		for _, ep := range bf.pool.endpoints {
+			ep.limiter = newRateLimiter(bf.rateLimit)
+			metrics.RPCComputeUnitBudget.WithLabelValues(ep.Name).Set(bf.rateLimit.ComputeUnitsPerSecond)
 		}
-		count++
-		return block, err
-	}, backoff.WithMaxTries(5))
+	}
+	return bf
+}
 
-	return block, err
+// intercepted wraps bf in its interceptor chain.
+func (bf *blockFetcher) intercepted() BlockFetcher {
+	return Intercept(bf, bf.interceptors...)
 }
 
-func (bf *blockFetcher) GetBlockNumberWithRetry(ctx context.Context) (uint64, error) {
-	st := time.Now()
-	defer func() {
-		slog.Info("Block number fetched", "duration", time.Since(st))
-	}()
-	count := 1
-	blockNumber, err := backoff.Retry(ctx, func() (uint64, error) {
-		slog.Info("Fetching block number", "attempt", count)
-		blockNumber, err := bf.client.BlockNumber(ctx)
+// scopeKey names state one blockFetcher keeps in the retry scope of a call.
+// A call making several queries over the same range tells them apart by part.
+type scopeKey struct {
+	bf       *blockFetcher
+	name     string
+	from, to uint64
+	part     int
+}
 
-		if err != nil {
-			if !isRetryableError(err) {
-				slog.Error("Non-retryable RPC error", "error", err, "type", "rpc_fatal")
-				metrics.RPCErrorsTotal.WithLabelValues("fatal").Inc()
-				return 0, backoff.Permanent(err)
-			}
-			slog.Warn("Retryable RPC error", "error", err, "type", "rpc_retry")
-			metrics.RPCErrorsTotal.WithLabelValues("retryable").Inc()
+// tried returns the endpoints earlier attempts of the current call went to,
+// so the next attempt fails over to another one.
+func (bf *blockFetcher) tried(ctx context.Context) *triedEndpoints {
+	return scopeValue(ctx, scopeKey{bf: bf, name: "tried"}, newTriedEndpoints)
+}
+
+// Fetch fetches a block with its transactions. Like every blockFetcher
+// method it makes a single attempt; retries come from the Retry interceptor.
+func (bf *blockFetcher) Fetch(ctx context.Context, blockNumber uint64) (*types.Block, error) {
+	return callEndpoint(ctx, bf.pool, bf.tried(ctx), func(ctx context.Context, ep *endpointState) (*types.Block, error) {
+		if err := ep.wait(ctx, "eth_getBlockByNumber"); err != nil {
+			return nil, err
 		}
-		count++
-		return blockNumber, err
-	}, backoff.WithMaxTries(5))
+		return ep.Client.BlockByNumber(ctx, new(big.Int).SetUint64(blockNumber))
+	})
+}
 
-	return blockNumber, err
+// FetchHeader fetches only the header of a block, without the transaction
+// bodies Fetch downloads. Prefer it whenever the transactions are not used.
+func (bf *blockFetcher) FetchHeader(ctx context.Context, blockNumber uint64) (*types.Header, error) {
+	return callEndpoint(ctx, bf.pool, bf.tried(ctx), func(ctx context.Context, ep *endpointState) (*types.Header, error) {
+		if err := ep.wait(ctx, "eth_getBlockByNumber"); err != nil {
+			return nil, err
+		}
+		return ep.Client.HeaderByNumber(ctx, new(big.Int).SetUint64(blockNumber))
+	})
 }
 
-// GetLogsInRange fetches logs from startBlock to endBlock with retry logic.
-func (bf *blockFetcher) GetLogsInRange(ctx context.Context, startBlock, endBlock uint64) ([]types.Log, error) {
-	st := time.Now()
-	defer func() {
-		slog.Info("Logs fetched", "startBlock", startBlock, "endBlock", endBlock, "duration", time.Since(st))
-	}()
-	count := 1
-	logs, err := backoff.Retry(ctx, func() ([]types.Log, error) {
-		slog.Info("Fetching logs", "startBlock", startBlock, "endBlock", endBlock, "attempt", count)
-		query := ethereum.FilterQuery{
-			FromBlock: big.NewInt(int64(startBlock)),
-			ToBlock:   big.NewInt(int64(endBlock)),
+// GetBlockNumber fetches the chain head.
+func (bf *blockFetcher) GetBlockNumber(ctx context.Context) (uint64, error) {
+	return callEndpoint(ctx, bf.pool, bf.tried(ctx), func(ctx context.Context, ep *endpointState) (uint64, error) {
+		if err := ep.wait(ctx, "eth_blockNumber"); err != nil {
+			return 0, err
 		}
-		logs, err := bf.client.FilterLogs(ctx, query)
+		return ep.Client.BlockNumber(ctx)
+	})
+}
 
+// GetTaggedBlockNumber fetches the number of the block a tag such as
+// rpc.SafeBlockNumber or rpc.FinalizedBlockNumber points to. Nodes that do
+// not track the tag yield ErrBlockTagUnsupported.
+func (bf *blockFetcher) GetTaggedBlockNumber(ctx context.Context, tag rpc.BlockNumber) (uint64, error) {
+	if tag >= 0 {
+		return 0, fmt.Errorf("%d is a block number, not a tag", tag)
+	}
+	return callEndpoint(ctx, bf.pool, bf.tried(ctx), func(ctx context.Context, ep *endpointState) (uint64, error) {
+		if err := ep.wait(ctx, "eth_getBlockByNumber"); err != nil {
+			return 0, err
+		}
+		header, err := ep.Client.HeaderByNumber(ctx, big.NewInt(tag.Int64()))
 		if err != nil {
-			if !isRetryableError(err) {
-				slog.Error("Non-retryable RPC error fetching logs", "error", err, "type", "rpc_fatal")
-				metrics.RPCErrorsTotal.WithLabelValues("fatal").Inc()
-				return nil, backoff.Permanent(err)
+			if isBlockTagUnsupported(err) {
+				return 0, fmt.Errorf("%w: %s: %v", ErrBlockTagUnsupported, tag, err)
 			}
-			slog.Warn("Retryable RPC error fetching logs", "error", err, "type", "rpc_retry")
-			metrics.RPCErrorsTotal.WithLabelValues("retryable").Inc()
+			return 0, err
 		}
-		count++
-		return logs, err
-	}, backoff.WithMaxTries(5))
+		return header.Number.Uint64(), nil
+	})
+}
 
-	return logs, err
+// GetLogsInRange fetches logs from startBlock to endBlock.
+// Ranges the provider rejects as too large are split, see filterLogs.
+func (bf *blockFetcher) GetLogsInRange(ctx context.Context, startBlock, endBlock uint64) ([]types.Log, error) {
+	return bf.filterLogs(ctx, 0, ethereum.FilterQuery{}, startBlock, endBlock)
 }
 
+// GetERC20TransfersInRange fetches the logs matching the fetcher's log
+// filters, ERC20 Transfer logs unless WithLogFilters says otherwise, from
+// startBlock to endBlock.
 func (bf *blockFetcher) GetERC20TransfersInRange(ctx context.Context, startBlock, endBlock uint64) ([]types.Log, error) {
-	st := time.Now()
-	defer func() {
-		slog.Info("ERC20 transfer logs fetched", "startBlock", startBlock, "endBlock", endBlock, "duration", time.Since(st))
-	}()
-	count := 1
-	logs, err := backoff.Retry(ctx, func() ([]types.Log, error) {
-		slog.Info("Fetching ERC20 transfer logs", "startBlock", startBlock, "endBlock", endBlock, "attempt", count)
+	sets := make([][]types.Log, len(bf.logFilters))
+	for i, filter := range bf.logFilters {
 		query := ethereum.FilterQuery{
-			FromBlock: big.NewInt(int64(startBlock)),
-			ToBlock:   big.NewInt(int64(endBlock)),
-			Topics:    [][]common.Hash{{erc20TransferEventHash}},
+			Addresses: filter.Addresses,
+			Topics:    filter.Topics,
 		}
-		logs, err := bf.client.FilterLogs(ctx, query)
-
+		logs, err := bf.filterLogs(ctx, i, query, startBlock, endBlock)
 		if err != nil {
-			if !isRetryableError(err) {
-				slog.Error("Non-retryable RPC error fetching ERC20 transfer logs", "error", err, "type", "rpc_fatal")
-				metrics.RPCErrorsTotal.WithLabelValues("fatal").Inc()
-				return nil, backoff.Permanent(err)
-			}
-			slog.Warn("Retryable RPC error fetching ERC20 transfer logs", "error", err, "type", "rpc_retry")
-			metrics.RPCErrorsTotal.WithLabelValues("retryable").Inc()
+			return nil, err
 		}
-		count++
-		return logs, err
-	}, backoff.WithMaxTries(5))
-
-	return logs, err
+		sets[i] = logs
+	}
+	return mergeLogs(sets...), nil
 }
+
 func DecodeERC20TransferLog(log types.Log) (from common.Address, to common.Address, value *big.Int, ok bool) {
 	if len(log.Topics) != 3 {
 		return common.Address{}, common.Address{}, nil, false
@@ -173,44 +217,3 @@ func DecodeERC20TransferLog(log types.Log) (from common.Address, to common.Addre
 
 	return from, to, value, true
 }
-
-func isRetryableError(err error) bool {
-	if err == nil {
-		return false
-	}
-
-	msg := err.Error()
-
-	switch {
-	case strings.Contains(msg, "timeout"):
-		return true
-	case strings.Contains(msg, "context deadline"):
-		return true
-	case strings.Contains(msg, "429"):
-		return true
-	case strings.Contains(msg, "504"):
-		return true
-	case strings.Contains(msg, "header not found"):
-		return true
-	case strings.Contains(msg, "no response"):
-		return true
-	case strings.Contains(msg, "connection reset by peer"):
-		return true
-	default:
-		return false
-	}
-}
-
-// func isRetryableRPCError(err error) bool {
-// 	// fixme: as we know we're using go-ethereum ethclient, we can't use rpc.Error type directly becasue it's json-rpc error
-// 	// so we have to find a way to check if the error is retryable
-// 	var rpcErr rpc.Error // go-ethereum rpc error
-// 	if errors.As(err, &rpcErr) {
-// 		return retryableCodes[rpcErr.ErrorCode()]
-// 	}
-// 	// Fallback to string checking
-// 	if isRetryableError(err) {
-// 		return true
-// 	}
-// 	return false
-// }
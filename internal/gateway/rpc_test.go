@@ -2,13 +2,95 @@ package gateway
 
 import (
 	"context"
-	"fmt"
+	"errors"
+	"flag"
+	"math/big"
+	"os"
 	"strings"
+	"sync"
 	"testing"
 
+	"github.com/KhanSufiyanMirza/evm-indexer-go/internal/testchain"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
 )
 
+var (
+	rpcURL         = flag.String("rpc", "", "run the live tests against this RPC instead of the fixtures in testdata/live")
+	recordFixtures = flag.Bool("record", false, "record the live responses into testdata/live, from -rpc or else a simulated chain")
+)
+
+const liveFixtures = "testdata/live"
+
+// liveFetcher returns a fetcher for the tests that need a real chain. By
+// default it replays testdata/live, which is recorded from a simulated chain
+// with
+//
+//	go test ./internal/gateway -run 'InRange$' -record
+//
+// or from a real one by adding -rpc https://eth.llamarpc.com.
+func liveFetcher(t *testing.T) BlockFetcher {
+	t.Helper()
+	if *rpcURL == "" {
+		if *recordFixtures {
+			recordSimulatedOnce.Do(func() { recordSimulated(t) })
+		}
+		fetcher, err := NewReplayFetcher(liveFixtures)
+		if err != nil {
+			t.Fatalf("Failed to load fixtures, run with -record to record them: %v", err)
+		}
+		return fetcher
+	}
+
+	client, err := ethclient.Dial(*rpcURL)
+	if err != nil {
+		t.Fatalf("Failed to dial RPC: %v", err)
+	}
+	t.Cleanup(client.Close)
+	fetcher := NewBlockFetcher(client)
+	if *recordFixtures {
+		fetcher, err = NewRecordingFetcher(fetcher, liveFixtures)
+		if err != nil {
+			t.Fatalf("Failed to create recording fetcher: %v", err)
+		}
+	}
+	return fetcher
+}
+
+var recordSimulatedOnce sync.Once
+
+// recordSimulated replaces testdata/live with the last blocks of a simulated
+// chain, each with a few token transfers, as the live tests fetch them.
+func recordSimulated(t *testing.T) {
+	t.Helper()
+	if err := os.RemoveAll(liveFixtures); err != nil {
+		t.Fatalf("Failed to remove old fixtures: %v", err)
+	}
+	chain := testchain.New(t)
+	for i := range 3 {
+		for j := range i + 1 {
+			chain.Transfer(common.BigToAddress(big.NewInt(int64(0xa11ce+j))), int64(i+1))
+		}
+		chain.Commit()
+	}
+	fetcher, err := NewRecordingFetcher(NewBlockFetcher(chain.Client), liveFixtures)
+	if err != nil {
+		t.Fatalf("Failed to create recording fetcher: %v", err)
+	}
+	ctx := context.Background()
+	head, err := fetcher.GetBlockNumber(ctx)
+	if err != nil {
+		t.Fatalf("Failed to record the head: %v", err)
+	}
+	if _, err := fetcher.GetLogsInRange(ctx, head-2, head); err != nil {
+		t.Fatalf("Failed to record logs: %v", err)
+	}
+	if _, err := fetcher.GetERC20TransfersInRange(ctx, head-2, head); err != nil {
+		t.Fatalf("Failed to record transfers: %v", err)
+	}
+}
+
 func TestERC20TransferEventHash(t *testing.T) {
 
 	expectedHashV2 := "ddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"
@@ -16,14 +98,48 @@ func TestERC20TransferEventHash(t *testing.T) {
 		t.Errorf("ERC20 Transfer Event hash (Keccak256) mismatch. Expected %s, got %s", expectedHashV2, erc20TransferEventHash.String())
 	}
 }
-func TestGetLogsInRange(t *testing.T) {
-	client, err := ethclient.Dial("https://eth.llamarpc.com")
+func TestFetchHeader(t *testing.T) {
+	node := newTestNode(3)
+	fetcher := NewBlockFetcher(node.dial(t))
+
+	header, err := fetcher.FetchHeader(context.Background(), 2)
 	if err != nil {
-		t.Fatalf("Failed to dial RPC: %v", err)
+		t.Fatalf("Failed to fetch header: %v", err)
 	}
-	defer client.Close()
-	fetcher := NewBlockFetcher(client)
-	endBlock, err := fetcher.GetBlockNumberWithRetry(context.Background())
+	if header.Hash() != node.headers[2].Hash() {
+		t.Errorf("expected hash %s, got %s", node.headers[2].Hash(), header.Hash())
+	}
+	if n := node.callCount("eth_getBlockByNumber/full"); n != 0 {
+		t.Errorf("expected no full block requests, got %d", n)
+	}
+}
+
+func TestGetTaggedBlockNumber(t *testing.T) {
+	node := newTestNode(10)
+	node.tags[rpc.FinalizedBlockNumber] = 4
+	fetcher := NewBlockFetcher(node.dial(t))
+
+	finalized, err := fetcher.GetTaggedBlockNumber(context.Background(), rpc.FinalizedBlockNumber)
+	if err != nil {
+		t.Fatalf("Failed to get finalized block: %v", err)
+	}
+	if finalized != 4 {
+		t.Errorf("expected finalized block 4, got %d", finalized)
+	}
+
+	calls := node.callCount("eth_getBlockByNumber")
+	_, err = fetcher.GetTaggedBlockNumber(context.Background(), rpc.SafeBlockNumber)
+	if !errors.Is(err, ErrBlockTagUnsupported) {
+		t.Fatalf("expected ErrBlockTagUnsupported, got %v", err)
+	}
+	if n := node.callCount("eth_getBlockByNumber") - calls; n != 1 {
+		t.Errorf("expected an unsupported tag not to be retried, got %d calls", n)
+	}
+}
+
+func TestGetLogsInRange(t *testing.T) {
+	fetcher := liveFetcher(t)
+	endBlock, err := fetcher.GetBlockNumber(context.Background())
 	if err != nil {
 		t.Fatalf("Failed to get latest block number: %v", err)
 	}
@@ -37,21 +153,24 @@ func TestGetLogsInRange(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to get logs in range: %v", err)
 	}
+	if len(logs) == 0 {
+		t.Fatalf("expected logs between blocks %d and %d", startBlock, endBlock)
+	}
 
 	t.Logf("Fetched %d logs between blocks %d and %d", len(logs), startBlock, endBlock)
 	for i, log := range logs {
-		t.Logf("Log %d: %+v", i, log)
+		if log.BlockNumber < startBlock || log.BlockNumber > endBlock {
+			t.Errorf("log %d: block %d out of range", i, log.BlockNumber)
+		}
+		if i > 0 && (log.BlockNumber < logs[i-1].BlockNumber || log.BlockNumber == logs[i-1].BlockNumber && log.Index <= logs[i-1].Index) {
+			t.Errorf("log %d: not in chain order", i)
+		}
 	}
-
 }
+
 func TestGetERC20TransfersInRange(t *testing.T) {
-	client, err := ethclient.Dial("https://eth.llamarpc.com")
-	if err != nil {
-		t.Fatalf("Failed to dial RPC: %v", err)
-	}
-	defer client.Close()
-	fetcher := NewBlockFetcher(client)
-	endBlock, err := fetcher.GetBlockNumberWithRetry(context.Background())
+	fetcher := liveFetcher(t)
+	endBlock, err := fetcher.GetBlockNumber(context.Background())
 	if err != nil {
 		t.Fatalf("Failed to get latest block number: %v", err)
 	}
@@ -65,17 +184,24 @@ func TestGetERC20TransfersInRange(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to get ERC20 Transfer logs in range: %v", err)
 	}
+	if len(logs) == 0 {
+		t.Fatalf("expected ERC20 Transfer logs between blocks %d and %d", startBlock, endBlock)
+	}
 
 	t.Logf("Fetched %d ERC20 Transfer logs between blocks %d and %d", len(logs), startBlock, endBlock)
 	for i, log := range logs {
+		if log.Topics[0] != erc20TransferEventHash {
+			t.Errorf("log %d: expected the Transfer signature, got %s", i, log.Topics[0])
+		}
+		if log.BlockNumber < startBlock || log.BlockNumber > endBlock {
+			t.Errorf("log %d: block %d out of range", i, log.BlockNumber)
+		}
+		// ERC721 transfers share the signature with the token ID indexed,
+		// only ERC20 ones decode.
 		from, to, value, ok := DecodeERC20TransferLog(log)
 		if !ok {
 			continue
 		}
-		t.Logf("ERC20 Transfer Log %d: from=%s, to=%s, value=%s", i, from, to, value)
-		logKey := fmt.Sprintf("%s-%d", log.TxHash.String(), log.Index)
-		t.Logf("Log Key %d: %s", i, logKey)
-
+		t.Logf("ERC20 Transfer Log %s-%d: from=%s, to=%s, value=%s", log.TxHash, log.Index, from, to, value)
 	}
-
 }
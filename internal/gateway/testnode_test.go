@@ -0,0 +1,310 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// testNode is an in-process JSON-RPC "eth" namespace serving a tiny fake chain.
+type testNode struct {
+	mu      sync.Mutex
+	headers map[uint64]*types.Header
+	logs    map[uint64][]types.Log
+	// failLogs makes the next N eth_getLogs calls for a block fail.
+	failLogs map[uint64]int
+	// logSpanLimit rejects eth_getLogs ranges wider than this many blocks (0 = unlimited).
+	logSpanLimit uint64
+	// reorgs replaces a block's header right after it has been served once.
+	reorgs map[uint64]*types.Header
+	// noBlockReceipts makes eth_getBlockReceipts answer "method not found".
+	noBlockReceipts bool
+	// tags maps the "safe" and "finalized" tags to a block number; a missing
+	// tag is answered like a node without finality does.
+	tags map[rpc.BlockNumber]uint64
+	// traces is what debug_traceBlockByHash returns per block number.
+	traces map[uint64][]txTrace
+	// pending is sent to every newPendingTransactions subscriber.
+	pending []*types.Transaction
+	// chainID is what eth_chainId returns.
+	chainID uint64
+	calls   map[string]int
+}
+
+type testFilter struct {
+	FromBlock *rpc.BlockNumber `json:"fromBlock"`
+	ToBlock   *rpc.BlockNumber `json:"toBlock"`
+	BlockHash *common.Hash     `json:"blockHash"`
+	Addresses []common.Address `json:"address"`
+	Topics    [][]common.Hash  `json:"topics"`
+}
+
+func newTestNode(blocks uint64) *testNode {
+	n := &testNode{
+		headers:  make(map[uint64]*types.Header),
+		logs:     make(map[uint64][]types.Log),
+		failLogs: make(map[uint64]int),
+		reorgs:   make(map[uint64]*types.Header),
+		tags:     make(map[rpc.BlockNumber]uint64),
+		traces:   make(map[uint64][]txTrace),
+		calls:    make(map[string]int),
+		chainID:  1,
+	}
+	parent := common.Hash{}
+	for num := uint64(0); num < blocks; num++ {
+		h := &types.Header{
+			ParentHash: parent,
+			Number:     new(big.Int).SetUint64(num),
+			Time:       1_700_000_000 + num*12,
+			Difficulty: big.NewInt(0),
+			UncleHash:  types.EmptyUncleHash,
+			TxHash:     types.EmptyTxsHash,
+		}
+		n.headers[num] = h
+		parent = h.Hash()
+		n.logs[num] = []types.Log{{
+			Address:     common.HexToAddress("0x00000000000000000000000000000000000000aa"),
+			Topics:      []common.Hash{erc20TransferEventHash, {}, {}},
+			Data:        big.NewInt(int64(num)).Bytes(),
+			BlockNumber: num,
+			BlockHash:   h.Hash(),
+			TxHash:      common.BigToHash(new(big.Int).SetUint64(num + 1)),
+		}}
+	}
+	return n
+}
+
+func (n *testNode) ChainId() (hexutil.Uint64, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.calls["eth_chainId"]++
+	return hexutil.Uint64(n.chainID), nil
+}
+
+func (n *testNode) BlockNumber() (hexutil.Uint64, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.calls["eth_blockNumber"]++
+	return hexutil.Uint64(len(n.headers) - 1), nil
+}
+
+func (n *testNode) GetBlockByNumber(number rpc.BlockNumber, full bool) (*types.Header, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.calls["eth_getBlockByNumber"]++
+	if full {
+		n.calls["eth_getBlockByNumber/full"]++
+	}
+	if number < 0 {
+		tagged, ok := n.tags[number]
+		if !ok {
+			return nil, fmt.Errorf("%s block not found", number)
+		}
+		number = rpc.BlockNumber(tagged)
+	}
+	header := n.headers[uint64(number)]
+	if sibling, ok := n.reorgs[uint64(number)]; ok {
+		n.headers[uint64(number)] = sibling
+		delete(n.reorgs, uint64(number))
+	}
+	return header, nil
+}
+
+func (n *testNode) GetLogs(filter testFilter) ([]types.Log, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.calls["eth_getLogs"]++
+	var from, to uint64
+	if filter.BlockHash != nil {
+		found := false
+		for num, h := range n.headers {
+			if h.Hash() == *filter.BlockHash {
+				from, to, found = num, num, true
+			}
+		}
+		if !found {
+			return nil, errors.New("unknown block")
+		}
+	} else {
+		from, to = uint64(*filter.FromBlock), uint64(*filter.ToBlock)
+	}
+	if n.logSpanLimit > 0 && to-from+1 > n.logSpanLimit {
+		return nil, testRPCError{code: -32005, msg: "query returned more than 10000 results"}
+	}
+	match := LogFilter{Addresses: filter.Addresses, Topics: filter.Topics}
+	logs := []types.Log{}
+	for num := from; num <= to; num++ {
+		if n.failLogs[num] > 0 {
+			n.failLogs[num]--
+			return nil, errors.New("request timeout")
+		}
+		for _, log := range n.logs[num] {
+			if match.Matches(log) {
+				logs = append(logs, log)
+			}
+		}
+	}
+	return logs, nil
+}
+
+func (n *testNode) GetBlockReceipts(block rpc.BlockNumberOrHash) ([]*types.Receipt, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.calls["eth_getBlockReceipts"]++
+	if n.noBlockReceipts {
+		return nil, testRPCError{code: -32601, msg: "the method eth_getBlockReceipts does not exist/is not available"}
+	}
+	num, ok := n.numberOf(*block.BlockHash)
+	if !ok {
+		return nil, nil
+	}
+	return n.receiptsOf(num), nil
+}
+
+func (n *testNode) GetBlockByHash(hash common.Hash, full bool) (map[string]any, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.calls["eth_getBlockByHash"]++
+	num, ok := n.numberOf(hash)
+	if !ok {
+		return nil, nil
+	}
+	txs := []common.Hash{}
+	for _, r := range n.receiptsOf(num) {
+		txs = append(txs, r.TxHash)
+	}
+	return map[string]any{"hash": hash, "transactions": txs}, nil
+}
+
+func (n *testNode) GetTransactionReceipt(hash common.Hash) (*types.Receipt, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.calls["eth_getTransactionReceipt"]++
+	for num := range n.headers {
+		for _, r := range n.receiptsOf(num) {
+			if r.TxHash == hash {
+				return r, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+func (n *testNode) numberOf(hash common.Hash) (uint64, bool) {
+	for num, h := range n.headers {
+		if h.Hash() == hash {
+			return num, true
+		}
+	}
+	return 0, false
+}
+
+// receiptsOf returns one successful receipt per transaction that emitted logs
+// in block num. Callers must hold n.mu.
+func (n *testNode) receiptsOf(num uint64) []*types.Receipt {
+	header := n.headers[num]
+	var receipts []*types.Receipt
+	for _, log := range n.logs[num] {
+		log := log
+		log.BlockHash = header.Hash()
+		receipts = append(receipts, &types.Receipt{
+			Status:            types.ReceiptStatusSuccessful,
+			CumulativeGasUsed: uint64(len(receipts)+1) * 50_000,
+			Logs:              []*types.Log{&log},
+			TxHash:            log.TxHash,
+			GasUsed:           50_000,
+			EffectiveGasPrice: big.NewInt(1_000_000_000),
+			BlockHash:         header.Hash(),
+			BlockNumber:       new(big.Int).SetUint64(num),
+			TransactionIndex:  uint(len(receipts)),
+		})
+	}
+	return receipts
+}
+
+// NewPendingTransactions serves eth_subscribe("newPendingTransactions", true).
+func (n *testNode) NewPendingTransactions(ctx context.Context, fullTx *bool) (*rpc.Subscription, error) {
+	notifier, ok := rpc.NotifierFromContext(ctx)
+	if !ok {
+		return nil, rpc.ErrNotificationsUnsupported
+	}
+	if fullTx == nil || !*fullTx {
+		return nil, errors.New("only full transactions are supported")
+	}
+	n.mu.Lock()
+	pending := n.pending
+	n.mu.Unlock()
+	sub := notifier.CreateSubscription()
+	go func() {
+		for _, tx := range pending {
+			if err := notifier.Notify(sub.ID, tx); err != nil {
+				return
+			}
+		}
+	}()
+	return sub, nil
+}
+
+// testDebug is the "debug" namespace of a testNode.
+type testDebug struct {
+	n *testNode
+}
+
+func (d testDebug) TraceBlockByHash(hash common.Hash, config map[string]any) ([]txTrace, error) {
+	d.n.mu.Lock()
+	defer d.n.mu.Unlock()
+	d.n.calls["debug_traceBlockByHash"]++
+	if config["tracer"] != "callTracer" {
+		return nil, fmt.Errorf("unexpected tracer %v", config["tracer"])
+	}
+	number, ok := d.n.numberOf(hash)
+	if !ok {
+		return nil, fmt.Errorf("block %s not found", hash.Hex())
+	}
+	traces := d.n.traces[number]
+	if traces == nil {
+		traces = []txTrace{}
+	}
+	return traces, nil
+}
+
+type testRPCError struct {
+	code int
+	msg  string
+}
+
+func (e testRPCError) Error() string  { return e.msg }
+func (e testRPCError) ErrorCode() int { return e.code }
+
+// dial serves the node in-process and returns a client connected to it.
+func (n *testNode) dial(t *testing.T) *ethclient.Client {
+	t.Helper()
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", n); err != nil {
+		t.Fatalf("Failed to register test node: %v", err)
+	}
+	if err := server.RegisterName("debug", testDebug{n}); err != nil {
+		t.Fatalf("Failed to register test node: %v", err)
+	}
+	client := ethclient.NewClient(rpc.DialInProc(server))
+	t.Cleanup(func() {
+		client.Close()
+		server.Stop()
+	})
+	return client
+}
+
+func (n *testNode) callCount(method string) int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.calls[method]
+}
@@ -0,0 +1,123 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// InternalTransfer is native value moved by a call made inside a transaction,
+// as opposed to the value of the transaction itself.
+type InternalTransfer struct {
+	TxHash common.Hash `json:"txHash"`
+	// TraceAddress is the position of the call in the transaction's call tree,
+	// e.g. [0 2] for the third call made by the first call of the transaction.
+	TraceAddress []int          `json:"traceAddress"`
+	CallType     string         `json:"callType"` // CALL, CREATE, CREATE2 or SELFDESTRUCT
+	From         common.Address `json:"from"`
+	To           common.Address `json:"to"`
+	Value        *big.Int       `json:"value"`
+}
+
+// TracePath formats TraceAddress as a dotted path, e.g. "0.2".
+func (t InternalTransfer) TracePath() string {
+	parts := make([]string, len(t.TraceAddress))
+	for i, n := range t.TraceAddress {
+		parts[i] = strconv.Itoa(n)
+	}
+	return strings.Join(parts, ".")
+}
+
+// errNoTraceTxHash is returned by nodes too old to include the transaction
+// hash in debug_traceBlockByHash results.
+var errNoTraceTxHash = errors.New("trace result without txHash")
+
+// callFrame is a call as reported by geth's callTracer.
+type callFrame struct {
+	Type  string          `json:"type"`
+	From  common.Address  `json:"from"`
+	To    *common.Address `json:"to"`
+	Value *hexutil.Big    `json:"value"`
+	Error string          `json:"error"`
+	Calls []callFrame     `json:"calls"`
+}
+
+type txTrace struct {
+	TxHash common.Hash `json:"txHash"`
+	Result *callFrame  `json:"result"`
+	Error  string      `json:"error"`
+}
+
+// GetInternalTransfers traces every transaction of the block with the given
+// hash with debug_traceBlockByHash and the callTracer, and returns the calls
+// below the top level that moved native value. Calls that reverted, or whose
+// caller reverted, moved nothing and are left out. The endpoint has to expose
+// the debug namespace. If the node does not know the hash the error wraps
+// ErrUnknownBlockHash.
+func (bf *blockFetcher) GetInternalTransfers(ctx context.Context, blockHash common.Hash) ([]InternalTransfer, error) {
+	traces, err := callEndpoint(ctx, bf.pool, bf.tried(ctx), func(ctx context.Context, ep *endpointState) ([]txTrace, error) {
+		if err := ep.wait(ctx, "debug_traceBlockByHash"); err != nil {
+			return nil, err
+		}
+		var traces []txTrace
+		err := ep.Client.Client().CallContext(ctx, &traces, "debug_traceBlockByHash",
+			blockHash, map[string]any{"tracer": "callTracer"})
+		return traces, err
+	})
+	if err != nil && ClassifyError(err) == ClassNodeLag {
+		return nil, fmt.Errorf("%w: %w", ErrUnknownBlockHash, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	transfers := []InternalTransfer{}
+	for i, trace := range traces {
+		if trace.Error != "" {
+			return nil, fmt.Errorf("tracing transaction %d of block %s: %s", i, blockHash, trace.Error)
+		}
+		if trace.TxHash == (common.Hash{}) {
+			return nil, fmt.Errorf("transaction %d of block %s: %w", i, blockHash, errNoTraceTxHash)
+		}
+		if trace.Result == nil || trace.Result.Error != "" {
+			continue
+		}
+		for j, call := range trace.Result.Calls {
+			transfers = collectInternalTransfers(transfers, trace.TxHash, []int{j}, call)
+		}
+	}
+	return transfers, nil
+}
+
+// collectInternalTransfers appends the value-bearing calls of the tree rooted
+// at frame, which sits at path in the call tree, to transfers.
+func collectInternalTransfers(transfers []InternalTransfer, txHash common.Hash, path []int, frame callFrame) []InternalTransfer {
+	if frame.Error != "" {
+		return transfers
+	}
+	switch frame.Type {
+	case "CALL", "CREATE", "CREATE2", "SELFDESTRUCT":
+		// DELEGATECALL and CALLCODE run code on behalf of the caller and
+		// STATICCALL cannot carry value, none of them move native value.
+		if frame.Value != nil && frame.To != nil && frame.Value.ToInt().Sign() > 0 {
+			transfers = append(transfers, InternalTransfer{
+				TxHash:       txHash,
+				TraceAddress: path,
+				CallType:     frame.Type,
+				From:         frame.From,
+				To:           *frame.To,
+				Value:        frame.Value.ToInt(),
+			})
+		}
+	}
+	for i, call := range frame.Calls {
+		transfers = collectInternalTransfers(transfers, txHash, append(path[:len(path):len(path)], i), call)
+	}
+	return transfers
+}
@@ -0,0 +1,79 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+func TestGetInternalTransfers(t *testing.T) {
+	addr := func(n int64) *common.Address {
+		a := common.BigToAddress(big.NewInt(n))
+		return &a
+	}
+	call := func(typ string, to, value int64, calls ...callFrame) callFrame {
+		return callFrame{Type: typ, From: *addr(1), To: addr(to), Value: (*hexutil.Big)(big.NewInt(value)), Calls: calls}
+	}
+	reverted := call("CALL", 6, 4, call("CALL", 7, 9))
+	reverted.Error = "execution reverted"
+	failedTx := call("CALL", 8, 1, call("CALL", 9, 1))
+	failedTx.Error = "out of gas"
+
+	node := newTestNode(3)
+	txHash := common.HexToHash("0x01")
+	node.traces[2] = []txTrace{
+		{TxHash: txHash, Result: ptr(call("CALL", 2, 1, // the transaction's own value is not internal
+			call("CALL", 3, 5, call("CALL", 4, 2)),
+			call("DELEGATECALL", 5, 7, call("CALL", 4, 3)),
+			reverted,
+			call("STATICCALL", 4, 0),
+		))},
+		{TxHash: common.HexToHash("0x02"), Result: &failedTx},
+	}
+	fetcher := NewBlockFetcher(node.dial(t))
+
+	transfers, err := fetcher.GetInternalTransfers(context.Background(), node.headers[2].Hash())
+	if err != nil {
+		t.Fatalf("Failed to get internal transfers: %v", err)
+	}
+	want := []struct {
+		path  string
+		to    int64
+		value int64
+	}{
+		{"0", 3, 5},
+		{"0.0", 4, 2},
+		{"1.0", 4, 3},
+	}
+	if len(transfers) != len(want) {
+		t.Fatalf("expected %d internal transfers, got %+v", len(want), transfers)
+	}
+	for i, w := range want {
+		got := transfers[i]
+		if got.TxHash != txHash || got.TracePath() != w.path || got.To != *addr(w.to) || got.Value.Int64() != w.value {
+			t.Errorf("transfer %d: expected %s to %d of %d, got %s to %s of %s", i, w.path, w.to, w.value, got.TracePath(), got.To, got.Value)
+		}
+	}
+
+	transfers, err = fetcher.GetInternalTransfers(context.Background(), node.headers[1].Hash())
+	if err != nil {
+		t.Fatalf("Failed to get internal transfers of an empty block: %v", err)
+	}
+	if transfers == nil || len(transfers) != 0 {
+		t.Errorf("expected an empty, non-nil result, got %#v", transfers)
+	}
+
+	// A block reorged out is unknown by its hash, rather than traced at its number.
+	fetcher = NewBlockFetcher(node.dial(t), WithInterceptors())
+	if _, err := fetcher.GetInternalTransfers(context.Background(), common.HexToHash("0x1f2e")); !errors.Is(err, ErrUnknownBlockHash) {
+		t.Errorf("expected ErrUnknownBlockHash, got %v", err)
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,90 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// ErrLogsMismatch is returned when the logs or receipts a provider returned
+// for a block are inconsistent with the block's header.
+var ErrLogsMismatch = errors.New("logs do not match the block header")
+
+// Reasons a block fails VerifyBlockLogs, used as the metrics label.
+const (
+	LogsMismatchBlockHash    = "block_hash"
+	LogsMismatchBloom        = "bloom"
+	LogsMismatchReceiptsRoot = "receipts_root"
+	LogsMismatchSet          = "log_set"
+)
+
+// LogsMismatchError tells why VerifyBlockLogs rejected a block.
+type LogsMismatchError struct {
+	Reason string // one of the LogsMismatch* constants
+	Detail string
+}
+
+func (e *LogsMismatchError) Error() string {
+	return fmt.Sprintf("%s: %s: %s", ErrLogsMismatch, e.Reason, e.Detail)
+}
+
+func (e *LogsMismatchError) Unwrap() error {
+	return ErrLogsMismatch
+}
+
+// VerifyBlockLogs checks the logs returned for a block with filters against
+// its header: every log must belong to the block and be covered by the
+// header's Bloom. A bloom cannot prove that no log is missing, so when
+// receipts are given they are checked against the header's ReceiptHash and
+// the logs have to be exactly the logs in the receipts that any of filters
+// matches.
+// Without receipts only wrong logs are caught, a provider dropping some of a
+// block's logs goes unnoticed.
+func VerifyBlockLogs(header *types.Header, logs []types.Log, receipts types.Receipts, filters []LogFilter) error {
+	hash := header.Hash()
+	for _, log := range logs {
+		if log.BlockHash != hash {
+			return &LogsMismatchError{Reason: LogsMismatchBlockHash, Detail: fmt.Sprintf("log %s-%d belongs to block %s, expected %s", log.TxHash, log.Index, log.BlockHash, hash)}
+		}
+		if !header.Bloom.Test(log.Address.Bytes()) {
+			return &LogsMismatchError{Reason: LogsMismatchBloom, Detail: fmt.Sprintf("address %s of log %s-%d is not in the bloom", log.Address, log.TxHash, log.Index)}
+		}
+		for _, topic := range log.Topics {
+			if !header.Bloom.Test(topic.Bytes()) {
+				return &LogsMismatchError{Reason: LogsMismatchBloom, Detail: fmt.Sprintf("topic %s of log %s-%d is not in the bloom", topic, log.TxHash, log.Index)}
+			}
+		}
+	}
+	if receipts == nil {
+		return nil
+	}
+
+	if root := types.DeriveSha(receipts, trie.NewStackTrie(nil)); root != header.ReceiptHash {
+		return &LogsMismatchError{Reason: LogsMismatchReceiptsRoot, Detail: fmt.Sprintf("receipts root %s, header has %s", root, header.ReceiptHash)}
+	}
+	type logKey struct {
+		txHash string
+		index  uint
+	}
+	expected := make(map[logKey]bool)
+	for _, r := range receipts {
+		for _, log := range r.Logs {
+			if matchesAny(filters, *log) {
+				expected[logKey{log.TxHash.Hex(), log.Index}] = true
+			}
+		}
+	}
+	for _, log := range logs {
+		key := logKey{log.TxHash.Hex(), log.Index}
+		if !expected[key] {
+			return &LogsMismatchError{Reason: LogsMismatchSet, Detail: fmt.Sprintf("log %s-%d is not in the receipts", log.TxHash, log.Index)}
+		}
+		delete(expected, key)
+	}
+	if len(expected) > 0 {
+		return &LogsMismatchError{Reason: LogsMismatchSet, Detail: fmt.Sprintf("%d matching logs in the receipts were not returned", len(expected))}
+	}
+	return nil
+}
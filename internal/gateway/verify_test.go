@@ -0,0 +1,78 @@
+package gateway
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+func TestVerifyBlockLogs(t *testing.T) {
+	transfer := func(tx byte, index uint) *types.Log {
+		return &types.Log{
+			Address: common.HexToAddress("0x00000000000000000000000000000000000000aa"),
+			Topics:  []common.Hash{erc20TransferEventHash, {}, {}},
+			TxHash:  common.Hash{tx},
+			Index:   index,
+		}
+	}
+	receipts := types.Receipts{
+		{Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: 50_000, TxHash: common.Hash{1}, Logs: []*types.Log{transfer(1, 0)}},
+		{Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: 100_000, TxHash: common.Hash{2}, Logs: []*types.Log{transfer(2, 1)}},
+	}
+	for _, r := range receipts {
+		r.Bloom = types.CreateBloom(r)
+	}
+	header := &types.Header{
+		Number:      big.NewInt(1),
+		Bloom:       types.MergeBloom(receipts),
+		ReceiptHash: types.DeriveSha(receipts, trie.NewStackTrie(nil)),
+	}
+	logsOf := func(ls ...*types.Log) []types.Log {
+		var logs []types.Log
+		for _, l := range ls {
+			l.BlockHash = header.Hash()
+			logs = append(logs, *l)
+		}
+		return logs
+	}
+
+	foreign := transfer(3, 0)
+	foreign.Address = common.HexToAddress("0x00000000000000000000000000000000000000bb")
+	otherBlock := logsOf(transfer(1, 0))
+	otherBlock[0].BlockHash = common.Hash{0xff}
+	tampered := *header
+	tampered.ReceiptHash = common.Hash{0xee}
+
+	for _, tc := range []struct {
+		name     string
+		header   *types.Header
+		logs     []types.Log
+		receipts types.Receipts
+		reason   string
+	}{
+		{name: "consistent", header: header, logs: logsOf(transfer(1, 0), transfer(2, 1)), receipts: receipts},
+		{name: "bloom only", header: header, logs: logsOf(transfer(1, 0))},
+		{name: "log of another block", header: header, logs: otherBlock, reason: LogsMismatchBlockHash},
+		{name: "log not in bloom", header: header, logs: logsOf(foreign), reason: LogsMismatchBloom},
+		{name: "missing log", header: header, logs: logsOf(transfer(1, 0)), receipts: receipts, reason: LogsMismatchSet},
+		{name: "receipts root", header: &tampered, logs: nil, receipts: receipts, reason: LogsMismatchReceiptsRoot},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := VerifyBlockLogs(tc.header, tc.logs, tc.receipts, []LogFilter{ERC20TransferFilter()})
+			if tc.reason == "" {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				return
+			}
+			var mismatch *LogsMismatchError
+			if !errors.As(err, &mismatch) || !errors.Is(err, ErrLogsMismatch) || mismatch.Reason != tc.reason {
+				t.Fatalf("expected %s mismatch, got %v", tc.reason, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package indexer
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/KhanSufiyanMirza/evm-indexer-go/db/sqlc"
+	"github.com/KhanSufiyanMirza/evm-indexer-go/internal/gateway"
+	"github.com/KhanSufiyanMirza/evm-indexer-go/internal/storage"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// LoadABIDir reads the contract ABIs in the *.json files of dir, keyed by
+// file name without the extension. A file holds either the ABI itself or a
+// build artifact (Hardhat, Foundry) with the ABI under "abi".
+func LoadABIDir(dir string) (map[string]abi.ABI, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	abis := make(map[string]abi.ABI, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var artifact struct {
+			ABI json.RawMessage `json:"abi"`
+		}
+		if json.Unmarshal(data, &artifact) == nil && len(artifact.ABI) > 0 {
+			data = artifact.ABI
+		}
+		parsed, err := abi.JSON(strings.NewReader(string(data)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse ABI %s: %w", path, err)
+		}
+		abis[strings.TrimSuffix(filepath.Base(path), ".json")] = parsed
+	}
+	return abis, nil
+}
+
+// abiHandler decodes the events of contracts with known ABIs into
+// decoded_events.
+type abiHandler struct {
+	contracts map[common.Address]abi.ABI
+}
+
+// NewABIHandler returns the handler decoding every event the ABI of a bound
+// contract declares, storing its arguments as JSON in decoded_events.
+// Anonymous events have no signature topic to be recognized by and are not
+// decoded. It fails without contracts or without any event to decode, the
+// handler's filter would match any log.
+func NewABIHandler(contracts map[common.Address]abi.ABI) (Handler, error) {
+	if len(contracts) == 0 {
+		return nil, errors.New("no contracts to decode the events of")
+	}
+	for _, contract := range contracts {
+		for _, event := range contract.Events {
+			if !event.Anonymous {
+				return abiHandler{contracts: contracts}, nil
+			}
+		}
+	}
+	return nil, errors.New("no contract ABI declares an event with a signature")
+}
+
+func (abiHandler) Name() string {
+	return "decoded_events"
+}
+
+// Filter matches the logs of the bound contracts with the signature of an
+// event in any of their ABIs, sorted for the query to be the same every run.
+func (h abiHandler) Filter() gateway.LogFilter {
+	var filter gateway.LogFilter
+	var events []common.Hash
+	for address, contract := range h.contracts {
+		filter.Addresses = append(filter.Addresses, address)
+		for _, event := range contract.Events {
+			if !event.Anonymous && !slices.Contains(events, event.ID) {
+				events = append(events, event.ID)
+			}
+		}
+	}
+	slices.SortFunc(filter.Addresses, func(a, b common.Address) int { return a.Cmp(b) })
+	slices.SortFunc(events, func(a, b common.Hash) int { return a.Cmp(b) })
+	filter.Topics = [][]common.Hash{events}
+	return filter
+}
+
+func (h abiHandler) Decode(logs []types.Log) (storage.EventRows, error) {
+	params := make(storage.DecodedEventRows, 0, len(logs))
+	for _, log := range logs {
+		contract, ok := h.contracts[log.Address]
+		if !ok || len(log.Topics) == 0 {
+			continue
+		}
+		event, err := contract.EventByID(log.Topics[0])
+		if err != nil {
+			// Matched the signature of an event another bound contract declares.
+			continue
+		}
+		args, err := decodeEventArgs(event, log)
+		if err != nil {
+			slog.Warn("Failed to decode event, skipping", "contract", log.Address.Hex(), "event", event.Sig, "tx", log.TxHash.String(), "logIndex", log.Index, "error", err)
+			continue
+		}
+		params = append(params, sqlc.BatchCreateDecodedEventParams{
+			TxHash:          log.TxHash.String(),
+			LogIndex:        int32(log.Index),
+			BlockNumber:     int64(log.BlockNumber),
+			BlockHash:       log.BlockHash.String(),
+			TxIndex:         int32(log.TxIndex),
+			ContractAddress: log.Address.Hex(),
+			EventName:       event.Name,
+			EventSignature:  event.Sig,
+			Args:            args,
+		})
+	}
+	return params, nil
+}
+
+// decodeEventArgs returns the arguments of log as a JSON object keyed by
+// argument name. Indexed arguments of dynamic types (strings, bytes, arrays)
+// are only in the log as their keccak256 hash, which is stored instead.
+func decodeEventArgs(event *abi.Event, log types.Log) ([]byte, error) {
+	args := make(map[string]any, len(event.Inputs))
+	if err := event.Inputs.UnpackIntoMap(args, log.Data); err != nil {
+		return nil, err
+	}
+	var indexed abi.Arguments
+	for _, input := range event.Inputs {
+		if input.Indexed {
+			indexed = append(indexed, input)
+		}
+	}
+	if err := abi.ParseTopicsIntoMap(args, indexed, log.Topics[1:]); err != nil {
+		return nil, err
+	}
+	for name, value := range args {
+		args[name] = jsonValue(reflect.ValueOf(value))
+	}
+	return json.Marshal(args)
+}
+
+// jsonValue converts a decoded ABI value for JSON: integers wider than 64
+// bits become decimal strings, as most JSON parsers would round them, and
+// byte strings and fixed-size byte arrays become hex. Tuples are decoded into
+// structs whose json tags hold the component names.
+func jsonValue(v reflect.Value) any {
+	if !v.IsValid() {
+		return nil
+	}
+	switch value := v.Interface().(type) {
+	case *big.Int:
+		return value.String()
+	case common.Address:
+		// Checksummed, like the addresses of the other tables.
+		return value.Hex()
+	case []byte:
+		return hexutil.Bytes(value)
+	case encoding.TextMarshaler:
+		// Hashes.
+		return value
+	}
+	switch v.Kind() {
+	case reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(b), v)
+			return hexutil.Bytes(b)
+		}
+		fallthrough
+	case reflect.Slice:
+		values := make([]any, v.Len())
+		for i := range values {
+			values[i] = jsonValue(v.Index(i))
+		}
+		return values
+	case reflect.Struct:
+		fields := make(map[string]any, v.NumField())
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			name := field.Tag.Get("json")
+			if name == "" {
+				name = field.Name
+			}
+			fields[name] = jsonValue(v.Field(i))
+		}
+		return fields
+	}
+	return v.Interface()
+}
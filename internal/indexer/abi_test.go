@@ -0,0 +1,129 @@
+package indexer
+
+import (
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/KhanSufiyanMirza/evm-indexer-go/internal/storage"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const vaultABI = `[
+	{"type": "event", "name": "Deposit", "inputs": [
+		{"name": "account", "type": "address", "indexed": true},
+		{"name": "memo", "type": "string", "indexed": true},
+		{"name": "amount", "type": "uint256"},
+		{"name": "data", "type": "bytes"},
+		{"name": "position", "type": "tuple", "components": [
+			{"name": "id", "type": "uint64"},
+			{"name": "tag", "type": "bytes4"}
+		]}
+	]},
+	{"type": "event", "name": "Ping", "anonymous": true, "inputs": []}
+]`
+
+func TestLoadABIDir(t *testing.T) {
+	dir := t.TempDir()
+	artifact := `{"contractName": "Vault", "abi": ` + vaultABI + `}`
+	for name, content := range map[string]string{"Vault.json": vaultABI, "VaultArtifact.json": artifact, "README.md": "not an ABI"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	abis, err := LoadABIDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(abis) != 2 {
+		t.Fatalf("expected 2 ABIs, got %d", len(abis))
+	}
+	for _, name := range []string{"Vault", "VaultArtifact"} {
+		if _, ok := abis[name].Events["Deposit"]; !ok {
+			t.Errorf("%s: expected the Deposit event", name)
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "Broken.json"), []byte(`{"abi": 1}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadABIDir(dir); err == nil {
+		t.Error("expected an error for an invalid ABI")
+	}
+}
+
+func TestABIHandler(t *testing.T) {
+	vault, err := abi.JSON(strings.NewReader(vaultABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := common.HexToAddress("0xbeef")
+	account := common.HexToAddress("0xa11ce")
+	h, err := NewABIHandler(map[common.Address]abi.ABI{address: vault})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewABIHandler(nil); err == nil {
+		t.Error("expected a handler without contracts to be rejected")
+	}
+	if _, err := NewABIHandler(map[common.Address]abi.ABI{address: {}}); err == nil {
+		t.Error("expected a handler without events to be rejected")
+	}
+
+	deposit := vault.Events["Deposit"]
+	amount, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	position := struct {
+		Id  uint64
+		Tag [4]byte
+	}{7, [4]byte{0xde, 0xad, 0xbe, 0xef}}
+	data, err := deposit.Inputs.NonIndexed().Pack(amount, []byte{0x01, 0x02}, position)
+	if err != nil {
+		t.Fatal(err)
+	}
+	memoHash := crypto.Keccak256Hash([]byte("hello"))
+	logs := []types.Log{
+		{Address: address, Topics: []common.Hash{deposit.ID, common.BytesToHash(account.Bytes()), memoHash}, Data: data, BlockNumber: 5, TxHash: common.HexToHash("0x01"), Index: 3},
+		// Same signature but the indexed memo is missing.
+		{Address: address, Topics: []common.Hash{deposit.ID, common.BytesToHash(account.Bytes())}, Data: data, BlockNumber: 5, TxHash: common.HexToHash("0x01"), Index: 4},
+		// Not a contract the handler knows.
+		{Address: common.HexToAddress("0xcafe"), Topics: []common.Hash{deposit.ID, common.BytesToHash(account.Bytes()), memoHash}, Data: data, BlockNumber: 5},
+	}
+
+	filter := h.Filter()
+	if !reflect.DeepEqual(filter.Addresses, []common.Address{address}) || !reflect.DeepEqual(filter.Topics, [][]common.Hash{{deposit.ID}}) {
+		t.Fatalf("unexpected filter %+v", filter)
+	}
+	rows, err := h.Decode(logs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := rows.(storage.DecodedEventRows)
+	if len(events) != 1 {
+		t.Fatalf("expected 1 decoded event, got %d", len(events))
+	}
+	event := events[0]
+	if event.EventName != "Deposit" || event.EventSignature != "Deposit(address,string,uint256,bytes,(uint64,bytes4))" || event.LogIndex != 3 || event.ContractAddress != address.Hex() {
+		t.Errorf("unexpected event %+v", event)
+	}
+	var args map[string]any
+	if err := json.Unmarshal(event.Args, &args); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"account":  account.Hex(),
+		"memo":     memoHash.Hex(),
+		"amount":   "123456789012345678901234567890",
+		"data":     "0x0102",
+		"position": map[string]any{"id": float64(7), "tag": "0xdeadbeef"},
+	}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("expected args %v, got %v", want, args)
+	}
+}
@@ -0,0 +1,191 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/KhanSufiyanMirza/evm-indexer-go/db/sqlc"
+	"github.com/KhanSufiyanMirza/evm-indexer-go/internal/gateway"
+	"github.com/KhanSufiyanMirza/evm-indexer-go/internal/metrics"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// backfillTimeout bounds each call fetching a backfill range, the log query
+// of a large range may need to be split many times.
+const backfillTimeout = 5 * time.Minute
+
+// errRangeUnlinked is returned when the headers of a backfill range do not
+// form a chain.
+var errRangeUnlinked = errors.New("headers do not link to their parents")
+
+// backfill indexes the blocks from start up to the finalized height, and at
+// most end, a range at a time: the headers of a range come from batch requests
+// and its logs from a single log query, grouped by block. Finalized
+// blocks are not reorged anymore, so instead of the per-block parent check a
+// range only has to link up with the block saved before it. Returns the last
+// block indexed, Run goes on from there one block at a time.
+func (i *Indexer) backfill(ctx context.Context, start, end int64) (int64, error) {
+	last := start - 1
+	finalized, err := i.finalizedHeight(ctx)
+	if err != nil {
+		slog.Warn("Failed to get the finalized height, skipping backfill", "error", err)
+		return last, nil
+	}
+	target := min(end, finalized)
+	if target < start {
+		return last, nil
+	}
+	parentHash, found, err := i.savedBlockHash(ctx, last)
+	if err != nil {
+		slog.Error("Failed to get previous block", "block", last, "error", err, "type", "db_fatal")
+		return last, fmt.Errorf("fatal db error getting previous block %d: %w", last, err)
+	}
+	slog.Info("Backfilling finalized blocks", "from", start, "to", target, "rangeSize", i.backfillRange)
+
+	refetches := 0
+	for from := start; from <= target; {
+		to := min(from+i.backfillRange-1, target)
+		startTimer := time.Now()
+
+		headers, logs, err := i.fetchRange(ctx, from, to)
+		if ctx.Err() != nil {
+			return last, nil
+		}
+		if pauseWhileCircuitOpen(ctx, from, err) {
+			continue
+		}
+		if err != nil {
+			slog.Error("Failed to fetch backfill range", "from", from, "to", to, "error", err)
+			return last, fmt.Errorf("failed to fetch blocks %d-%d: %w", from, to, err)
+		}
+		if found && headers[0].ParentHash != parentHash {
+			// The block saved before the range was reorged out before it
+			// was finalized, the per-block ingestion rolls it back.
+			slog.Warn("Backfill range does not link to the saved chain, switching to per-block ingestion", "block", from, "dbHash", parentHash.String(), "parentHash", headers[0].ParentHash.String())
+			return last, nil
+		}
+		if err := verifyRange(headers, logs, i.registry.Filters()); err != nil {
+			var mismatch *gateway.LogsMismatchError
+			if errors.As(err, &mismatch) {
+				metrics.LogVerificationFailuresTotal.WithLabelValues(mismatch.Reason).Inc()
+			}
+			slog.Error("ALERT: Backfill range failed verification", "from", from, "to", to, "error", err, "attempt", refetches+1)
+			if refetches < maxRefetches {
+				refetches++
+				continue
+			}
+			return last, fmt.Errorf("blocks %d-%d failed verification: %w", from, to, err)
+		}
+		refetches = 0
+
+		blockParams := make([]sqlc.BatchCreateBlockParams, len(headers))
+		var rangeLogs []types.Log
+		for n, header := range headers {
+			blockParams[n] = sqlc.BatchCreateBlockParams{
+				Hash:       header.Hash().String(),
+				Number:     header.Number.Int64(),
+				ParentHash: header.ParentHash.String(),
+				Timestamp:  time.Unix(int64(header.Time), 0),
+			}
+			rangeLogs = append(rangeLogs, logs[n]...)
+		}
+		events, err := i.registry.decode(rangeLogs)
+		if err != nil {
+			return last, fmt.Errorf("failed to decode logs of blocks %d-%d: %w", from, to, err)
+		}
+		// Like in Run, saving is not cut short by a shutdown signal.
+		opCtx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+		err = i.store.SaveBlockRange(opCtx, blockParams, events)
+		cancel()
+		if err != nil {
+			slog.Error("Failed to save backfill range", "from", from, "to", to, "error", err, "type", "db_fatal")
+			return last, fmt.Errorf("fatal db error saving blocks %d-%d: %w", from, to, err)
+		}
+		for _, header := range headers {
+			i.recent.add(header.Number.Int64(), header.Hash(), header.ParentHash)
+		}
+
+		last, found, parentHash = to, true, headers[len(headers)-1].Hash()
+		from = to + 1
+		metrics.BlocksProcessedTotal.Add(float64(len(headers)))
+		metrics.BackfillBlocksTotal.Add(float64(len(headers)))
+		metrics.CurrentBlockHeight.Set(float64(to))
+		metrics.BackfillRangeDuration.Observe(time.Since(startTimer).Seconds())
+		slog.Info("Backfilled blocks", "from", blockParams[0].Number, "to", to, "logs", len(rangeLogs))
+		for n, rows := range events {
+			metrics.IndexedEventsTotal.WithLabelValues(i.registry.handlers[n].Name()).Add(float64(rows.Len()))
+		}
+	}
+	return last, nil
+}
+
+// fetchRange fetches the headers of blocks from to to and their logs,
+// grouped by block.
+func (i *Indexer) fetchRange(ctx context.Context, from, to int64) ([]*types.Header, [][]types.Log, error) {
+	callCtx, cancel := context.WithTimeout(ctx, backfillTimeout)
+	headers, err := i.fetcher.GetHeadersInRange(callCtx, uint64(from), uint64(to))
+	cancel()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(headers) != int(to-from+1) {
+		return nil, nil, fmt.Errorf("expected %d headers from %d, got %d", to-from+1, from, len(headers))
+	}
+
+	callCtx, cancel = context.WithTimeout(ctx, backfillTimeout)
+	logs, err := i.fetcher.GetERC20TransfersInRange(callCtx, uint64(from), uint64(to))
+	cancel()
+	if err != nil {
+		return nil, nil, err
+	}
+	perBlock := make([][]types.Log, len(headers))
+	for _, log := range logs {
+		num := int64(log.BlockNumber)
+		if num < from || num > to {
+			return nil, nil, fmt.Errorf("log %s-%d of block %d is outside of range %d-%d", log.TxHash, log.Index, num, from, to)
+		}
+		perBlock[num-from] = append(perBlock[num-from], log)
+	}
+	return headers, perBlock, nil
+}
+
+// verifyRange checks that the headers form a chain and that the logs of
+// every block match its header.
+func verifyRange(headers []*types.Header, logs [][]types.Log, filters []gateway.LogFilter) error {
+	var parent common.Hash
+	for n, header := range headers {
+		if n > 0 && header.ParentHash != parent {
+			return fmt.Errorf("%w: block %d has parent %s, expected %s", errRangeUnlinked, header.Number, header.ParentHash, parent)
+		}
+		if err := gateway.VerifyBlockLogs(header, logs[n], nil, filters); err != nil {
+			return fmt.Errorf("block %d: %w", header.Number, err)
+		}
+		parent = header.Hash()
+	}
+	return nil
+}
+
+// finalizedHeight returns the highest block the chain finalized: the node's
+// "finalized" block, or the configured depth below the tip on chains whose
+// nodes do not serve the tag.
+func (i *Indexer) finalizedHeight(ctx context.Context) (int64, error) {
+	callCtx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	defer cancel()
+	finalized, err := i.fetcher.GetTaggedBlockNumber(callCtx, rpc.FinalizedBlockNumber)
+	if errors.Is(err, gateway.ErrBlockTagUnsupported) {
+		tip, err := i.fetcher.GetBlockNumber(callCtx)
+		if err != nil {
+			return 0, err
+		}
+		return int64(tip) - int64(i.backfillDepth), nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return int64(finalized), nil
+}
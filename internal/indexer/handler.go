@@ -0,0 +1,129 @@
+package indexer
+
+import (
+	"fmt"
+
+	"github.com/KhanSufiyanMirza/evm-indexer-go/db/sqlc"
+	"github.com/KhanSufiyanMirza/evm-indexer-go/internal/gateway"
+	"github.com/KhanSufiyanMirza/evm-indexer-go/internal/storage"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Handler indexes one kind of event. Run hands every handler the logs of each
+// block its Filter matches and saves what it decoded in the block's
+// transaction. Reorgs are rolled back by the store, which marks the table of
+// every handler non-canonical whether it is registered or not, so a handler
+// writing to a new table adds it to storage.Store.MarkBlockReorgedRange.
+type Handler interface {
+	// Name identifies the handler in logs and metrics.
+	Name() string
+	// Filter selects the logs the handler wants.
+	Filter() gateway.LogFilter
+	// Decode turns logs matching Filter into rows. The logs are in chain
+	// order and may span several blocks. Logs it cannot decode are skipped.
+	Decode(logs []types.Log) (storage.EventRows, error)
+}
+
+// Registry holds the handlers an Indexer runs, in registration order.
+type Registry struct {
+	handlers []Handler
+}
+
+// NewRegistry returns a registry of handlers, see Register.
+func NewRegistry(handlers ...Handler) (*Registry, error) {
+	r := &Registry{}
+	for _, h := range handlers {
+		if err := r.Register(h); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// DefaultRegistry returns a registry with the ERC20 Transfer handler.
+func DefaultRegistry() *Registry {
+	return &Registry{handlers: []Handler{NewERC20TransferHandler()}}
+}
+
+// Register adds a handler. Names must be unique.
+func (r *Registry) Register(h Handler) error {
+	for _, registered := range r.handlers {
+		if registered.Name() == h.Name() {
+			return fmt.Errorf("handler %q already registered", h.Name())
+		}
+	}
+	r.handlers = append(r.handlers, h)
+	return nil
+}
+
+// Filters returns the filters of all handlers, merged into one per set of
+// contracts, for the fetcher to get every handler's logs with as few queries
+// per block as possible (see gateway.WithLogFilters).
+func (r *Registry) Filters() []gateway.LogFilter {
+	filters := make([]gateway.LogFilter, len(r.handlers))
+	for i, h := range r.handlers {
+		filters[i] = h.Filter()
+	}
+	return gateway.MergeLogFilters(filters...)
+}
+
+// decode hands each handler the logs its filter matches and returns what
+// they decoded, in registration order.
+func (r *Registry) decode(logs []types.Log) ([]storage.EventRows, error) {
+	events := make([]storage.EventRows, 0, len(r.handlers))
+	for _, h := range r.handlers {
+		filter := h.Filter()
+		var matched []types.Log
+		for _, log := range logs {
+			if filter.Matches(log) {
+				matched = append(matched, log)
+			}
+		}
+		rows, err := h.Decode(matched)
+		if err != nil {
+			return nil, fmt.Errorf("handler %s failed to decode logs: %w", h.Name(), err)
+		}
+		events = append(events, rows)
+	}
+	return events, nil
+}
+
+// erc20TransferHandler indexes ERC20 Transfer events into erc20_transfers.
+type erc20TransferHandler struct{}
+
+// NewERC20TransferHandler returns the handler indexing ERC20 Transfer events
+// of every contract into erc20_transfers.
+func NewERC20TransferHandler() Handler {
+	return erc20TransferHandler{}
+}
+
+func (erc20TransferHandler) Name() string {
+	return "erc20_transfers"
+}
+
+func (erc20TransferHandler) Filter() gateway.LogFilter {
+	return gateway.ERC20TransferFilter()
+}
+
+func (erc20TransferHandler) Decode(logs []types.Log) (storage.EventRows, error) {
+	params := make(storage.ERC20TransferRows, 0, len(logs))
+	for _, transferLog := range logs {
+		from, to, value, ok := gateway.DecodeERC20TransferLog(transferLog)
+		if !ok {
+			// NOTE: ERC721 Transfer event has 4 topics and ERC20 Transfer event has 3 topics both have same signature
+			// so we can't differentiate between them just by signature
+			continue
+		}
+		params = append(params, sqlc.BatchCreateERC20TransferParams{
+			TxHash:       transferLog.TxHash.String(),
+			LogIndex:     int32(transferLog.Index),
+			BlockNumber:  int64(transferLog.BlockNumber),
+			FromAddress:  from.Hex(),
+			ToAddress:    to.Hex(),
+			Value:        pgtype.Numeric{Int: value, Valid: true},
+			TokenAddress: transferLog.Address.Hex(),
+		})
+	}
+	return params, nil
+}
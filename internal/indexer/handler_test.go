@@ -0,0 +1,74 @@
+package indexer
+
+import (
+	"context"
+	"math/big"
+	"slices"
+	"testing"
+
+	"github.com/KhanSufiyanMirza/evm-indexer-go/db/sqlc"
+	"github.com/KhanSufiyanMirza/evm-indexer-go/internal/gateway"
+	"github.com/KhanSufiyanMirza/evm-indexer-go/internal/storage"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// logCounter is a handler keeping the logs its filter matched.
+type logCounter struct {
+	name   string
+	filter gateway.LogFilter
+}
+
+type countedLogs []types.Log
+
+func (l countedLogs) Len() int                                        { return len(l) }
+func (l countedLogs) Save(ctx context.Context, q *sqlc.Queries) error { return nil }
+
+func (c logCounter) Name() string              { return c.name }
+func (c logCounter) Filter() gateway.LogFilter { return c.filter }
+func (c logCounter) Decode(logs []types.Log) (storage.EventRows, error) {
+	return countedLogs(logs), nil
+}
+
+func TestRegistry(t *testing.T) {
+	transfer := crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+	approval := crypto.Keccak256Hash([]byte("Approval(address,address,uint256)"))
+	token := common.HexToAddress("0xcafe")
+	approvals := logCounter{"approvals", gateway.LogFilter{Addresses: []common.Address{token}, Topics: [][]common.Hash{{approval}}}}
+
+	r, err := NewRegistry(NewERC20TransferHandler(), approvals)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Register(logCounter{name: "approvals"}); err == nil {
+		t.Fatal("expected a duplicate handler name to be rejected")
+	}
+
+	value := common.BigToHash(big.NewInt(7))
+	logs := []types.Log{
+		{Address: token, Topics: []common.Hash{transfer, {}, {}}, Data: value.Bytes(), BlockNumber: 1},
+		{Address: token, Topics: []common.Hash{approval, {}, {}}, Data: value.Bytes(), BlockNumber: 1},
+		// An ERC721 transfer matches the filter but is not decoded.
+		{Address: token, Topics: []common.Hash{transfer, {}, {}, {}}, BlockNumber: 2},
+	}
+	filters := r.Filters()
+	if len(filters) != 2 {
+		t.Errorf("expected a filter for all contracts and one for the token, got %+v", filters)
+	}
+	// The token's approvals are not fetched from other contracts.
+	foreign := types.Log{Address: common.HexToAddress("0xbeef"), Topics: []common.Hash{approval, {}, {}}, BlockNumber: 2}
+	if slices.ContainsFunc(filters, func(f gateway.LogFilter) bool { return f.Matches(foreign) }) {
+		t.Errorf("expected the filters not to match %+v", foreign)
+	}
+	events, err := r.decode(logs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].Len() != 1 || events[1].Len() != 1 {
+		t.Fatalf("expected one transfer and one approval, got %+v", events)
+	}
+	if transfers := events[0].(storage.ERC20TransferRows); transfers[0].Value.Int.Int64() != 7 {
+		t.Errorf("unexpected transfer %+v", transfers[0])
+	}
+}
@@ -0,0 +1,240 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/KhanSufiyanMirza/evm-indexer-go/db/sqlc"
+	"github.com/KhanSufiyanMirza/evm-indexer-go/internal/gateway"
+	"github.com/KhanSufiyanMirza/evm-indexer-go/internal/metrics"
+	"github.com/KhanSufiyanMirza/evm-indexer-go/internal/storage"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	// pendingFlushInterval and pendingFlushSize bound how long and how many
+	// pending transactions are buffered before being written in one batch.
+	pendingFlushInterval = time.Second
+	pendingFlushSize     = 500
+	// reconcileInterval is how often pending transactions are checked against
+	// the blocks indexed since, and stale ones dropped.
+	reconcileInterval = 12 * time.Second
+	// maxReconcileBlocks bounds how many blocks one reconciliation fetches.
+	maxReconcileBlocks = 100
+)
+
+// Mempool stores the transactions seen in the mempool in pending_transactions
+// and follows them until they are mined (CONFIRMED), another transaction with
+// the same sender and nonce is (REPLACED), or they have been pending for
+// longer than a timeout (DROPPED). A dropped transaction that gets mined
+// after all is still confirmed.
+type Mempool struct {
+	watcher *gateway.PendingTxWatcher
+	fetcher gateway.BlockFetcher
+	store   *storage.Store
+	timeout time.Duration
+	// reconciled is the highest block whose transactions were reconciled,
+	// -1 until the first reconciliation.
+	reconciled int64
+	// reconciledHashes holds the hashes of the last reconciled blocks, to
+	// tell when a reorg replaced them.
+	reconciledHashes map[int64]string
+}
+
+func NewMempool(watcher *gateway.PendingTxWatcher, fetcher gateway.BlockFetcher, store *storage.Store, timeout time.Duration) *Mempool {
+	return &Mempool{
+		watcher:    watcher,
+		fetcher:    fetcher,
+		store:      store,
+		timeout:    timeout,
+		reconciled: -1,
+
+		reconciledHashes: make(map[int64]string),
+	}
+}
+
+// Run watches the mempool and reconciles pending transactions with the blocks
+// the indexer processes until ctx is cancelled. Reconciliation starts from the
+// highest block processed when Run starts.
+func (m *Mempool) Run(ctx context.Context) error {
+	go m.watcher.Run(ctx)
+
+	flush := time.NewTicker(pendingFlushInterval)
+	defer flush.Stop()
+	reconcile := time.NewTicker(reconcileInterval)
+	defer reconcile.Stop()
+
+	var batch []sqlc.BatchCreatePendingTransactionParams
+	save := func() {
+		if len(batch) == 0 {
+			return
+		}
+		opCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := m.store.SavePendingTransactionBatch(opCtx, batch); err != nil {
+			slog.Error("Failed to save pending transactions", "count", len(batch), "error", err, "type", "db_fatal")
+		}
+		batch = batch[:0]
+	}
+
+	m.reconcile()
+	for {
+		select {
+		case <-ctx.Done():
+			save()
+			slog.Info("Mempool shutting down", "reconciledUpTo", m.reconciled)
+			return nil
+		case tx := <-m.watcher.Pending():
+			batch = append(batch, pendingTxParams(tx))
+			if len(batch) >= pendingFlushSize {
+				save()
+			}
+		case <-flush.C:
+			save()
+		case <-reconcile.C:
+			// Save first, a transaction seen just before being mined is confirmed right away.
+			save()
+			m.reconcile()
+		}
+	}
+}
+
+// reconcile settles the pending transactions mined in the blocks processed
+// since the last call, and drops the stale ones.
+func (m *Mempool) reconcile() {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	processed, err := m.store.GetLatestProcessedBlockNumber(ctx)
+	switch {
+	case errors.Is(err, storage.ErrBlockNotFound):
+		processed = -1
+	case err != nil:
+		slog.Error("Failed to get latest processed block", "error", err)
+		return
+	}
+	if m.reconciled < 0 {
+		m.reconciled = processed
+	} else if err := m.rewind(ctx, processed); err != nil {
+		slog.Error("Failed to check reconciled blocks for reorgs", "error", err, "type", "db_fatal")
+		return
+	}
+	end := min(processed, m.reconciled+maxReconcileBlocks)
+	for n := m.reconciled + 1; n <= end; n++ {
+		hash, err := m.reconcileBlock(ctx, n)
+		if err != nil {
+			slog.Error("Failed to reconcile pending transactions", "block", n, "error", err)
+			break
+		}
+		m.reconciled = n
+		m.reconciledHashes[n] = hash
+		delete(m.reconciledHashes, n-defaultRecentBlocks)
+	}
+
+	dropped, err := m.store.DropStalePendingTransactions(ctx, m.timeout)
+	if err != nil {
+		slog.Error("Failed to drop stale pending transactions", "error", err, "type", "db_fatal")
+		return
+	}
+	if dropped > 0 {
+		metrics.PendingTransactionsTotal.WithLabelValues("dropped").Add(float64(dropped))
+		slog.Info("Dropped stale pending transactions", "count", dropped, "timeout", m.timeout)
+	}
+}
+
+// rewind moves the reconciled block back to the highest one still indexed
+// with the hash it was reconciled at. A reorg rolled the blocks above it back,
+// which reset their transactions to pending, even when the indexer has since
+// replaced them up to the same height or higher, so the blocks that replaced
+// them are reconciled again.
+func (m *Mempool) rewind(ctx context.Context, processed int64) error {
+	for m.reconciled >= 0 {
+		hash, ok := m.reconciledHashes[m.reconciled]
+		if !ok {
+			// Deeper than any reorg, or the block Run started from.
+			m.reconciled = min(m.reconciled, processed)
+			return nil
+		}
+		if m.reconciled <= processed {
+			saved, err := m.store.GetBlockByNumber(ctx, m.reconciled)
+			if err != nil && !errors.Is(err, storage.ErrBlockNotFound) {
+				return err
+			}
+			if err == nil && saved.Hash == hash {
+				return nil
+			}
+		}
+		slog.Info("Reconciled block was reorged out, reconciling its replacement", "block", m.reconciled, "hash", hash)
+		delete(m.reconciledHashes, m.reconciled)
+		m.reconciled--
+	}
+	return nil
+}
+
+// reconcileBlock confirms the pending transactions mined in block n and marks
+// those they replaced. It returns the hash of the block.
+func (m *Mempool) reconcileBlock(ctx context.Context, n int64) (string, error) {
+	block, err := m.fetcher.Fetch(ctx, uint64(n))
+	if err != nil {
+		return "", err
+	}
+	saved, err := m.store.GetBlockByNumber(ctx, n)
+	if err != nil {
+		return "", err
+	}
+	if block.Hash().String() != saved.Hash {
+		// The indexer has yet to catch up with a reorg, try again next time.
+		return "", fmt.Errorf("fetched block %s but indexed %s", block.Hash(), saved.Hash)
+	}
+
+	blockNumber := pgtype.Int8{Int64: n, Valid: true}
+	txHashes := make([]string, 0, len(block.Transactions()))
+	replacements := make([]sqlc.BatchReplacePendingTransactionParams, 0, len(block.Transactions()))
+	for _, tx := range block.Transactions() {
+		txHashes = append(txHashes, tx.Hash().String())
+		from, err := gateway.TxSender(tx)
+		if err != nil {
+			// e.g. L2 deposit transactions, which are never in the mempool.
+			slog.Debug("Cannot recover transaction sender", "txHash", tx.Hash(), "error", err)
+			continue
+		}
+		replacements = append(replacements, sqlc.BatchReplacePendingTransactionParams{
+			FromAddress: from.Hex(),
+			Nonce:       int64(tx.Nonce()),
+			ReplacedBy:  pgtype.Text{String: tx.Hash().String(), Valid: true},
+			BlockNumber: blockNumber,
+		})
+	}
+
+	confirmed, err := m.store.ConfirmPendingTransactions(ctx, n, txHashes)
+	if err != nil {
+		return "", err
+	}
+	metrics.PendingTransactionsTotal.WithLabelValues("confirmed").Add(float64(confirmed))
+	err = m.store.ReplacePendingTransactionBatch(ctx, replacements)
+	if err != nil {
+		return "", err
+	}
+	slog.Debug("Reconciled pending transactions", "block", n, "confirmed", confirmed)
+	return saved.Hash, nil
+}
+
+// pendingTxParams converts a pending transaction to its row.
+func pendingTxParams(tx gateway.PendingTx) sqlc.BatchCreatePendingTransactionParams {
+	var to pgtype.Text
+	if tx.Tx.To() != nil {
+		to = pgtype.Text{String: tx.Tx.To().Hex(), Valid: true}
+	}
+	return sqlc.BatchCreatePendingTransactionParams{
+		TxHash:      tx.Tx.Hash().String(),
+		FromAddress: tx.From.Hex(),
+		ToAddress:   to,
+		Nonce:       int64(tx.Tx.Nonce()),
+		Value:       pgtype.Numeric{Int: tx.Tx.Value(), Valid: true},
+		GasFeeCap:   pgtype.Numeric{Int: tx.Tx.GasFeeCap(), Valid: true},
+		Input:       tx.Tx.Data(),
+	}
+}
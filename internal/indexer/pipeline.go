@@ -0,0 +1,204 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/KhanSufiyanMirza/evm-indexer-go/internal/gateway"
+	"github.com/KhanSufiyanMirza/evm-indexer-go/internal/metrics"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	// defaultFetchWorkers is how many windows are fetched concurrently.
+	defaultFetchWorkers = 4
+	// fetchTimeout bounds each call a fetch worker makes.
+	fetchTimeout = 60 * time.Second
+)
+
+// fetchedBlock is everything Run needs to save a block.
+type fetchedBlock struct {
+	header            *types.Header
+	logs              []types.Log
+	receipts          types.Receipts
+	internalTransfers []gateway.InternalTransfer
+}
+
+// fetchWindow is blocks from to to, being fetched until done is closed.
+type fetchWindow struct {
+	from, to int64
+	blocks   []fetchedBlock
+	err      error
+	done     chan struct{}
+}
+
+// pipeline fetches consecutive windows of blocks ahead of Run with a pool of
+// workers, and hands the blocks out in order. At most workers windows are
+// fetched concurrently and at most workers+1 are held, fetched or in flight,
+// ahead of the block being saved, which bounds memory on long backfills.
+//
+// Run still saves one block at a time and checks it against its parent: when
+// it asks for another block than the next one (after a reorg, or to refetch a
+// block), the pipeline drops what it fetched and starts over from there.
+type pipeline struct {
+	fetch   func(ctx context.Context, from, to int64) ([]fetchedBlock, error)
+	window  int64
+	workers int
+	end     int64
+
+	cancel  context.CancelFunc
+	queue   chan *fetchWindow // windows in order, closed when the last one is queued
+	current *fetchWindow
+	next    int64 // the block Run is expected to ask for next
+}
+
+func newPipeline(fetch func(ctx context.Context, from, to int64) ([]fetchedBlock, error), window int64, workers int, end int64) *pipeline {
+	return &pipeline{
+		fetch:   fetch,
+		window:  window,
+		workers: max(workers, 1),
+		end:     end,
+	}
+}
+
+// block returns block num, waiting for its window to be fetched until ctx is
+// done; every fetch call is bounded by fetchTimeout already. A failed window
+// is dropped, so asking for the block again refetches it.
+func (p *pipeline) block(ctx context.Context, num int64) (fetchedBlock, error) {
+	if p.queue == nil || num != p.next {
+		p.stop()
+		p.start(num)
+	}
+	if p.current == nil || num > p.current.to {
+		w, ok := <-p.queue
+		if !ok {
+			return fetchedBlock{}, fmt.Errorf("block %d is past the end of the pipeline", num)
+		}
+		p.current = w
+	}
+
+	w := p.current
+	select {
+	case <-w.done:
+	default:
+		waitStart := time.Now()
+		select {
+		case <-w.done:
+		case <-ctx.Done():
+			return fetchedBlock{}, ctx.Err()
+		}
+		metrics.PipelineCommitWaitSeconds.Add(time.Since(waitStart).Seconds())
+	}
+	if w.err != nil {
+		p.stop()
+		return fetchedBlock{}, w.err
+	}
+	p.next = num + 1
+	metrics.PipelineBufferedBlocks.Dec()
+	return w.blocks[num-w.from], nil
+}
+
+// start schedules the windows from block from to the end.
+func (p *pipeline) start(from int64) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	p.queue = make(chan *fetchWindow, p.workers)
+	p.next = from
+
+	queue := p.queue
+	sem := make(chan struct{}, p.workers)
+	go func() {
+		defer close(queue)
+		for ; from <= p.end && ctx.Err() == nil; from += p.window {
+			w := &fetchWindow{from: from, to: min(from+p.window-1, p.end), done: make(chan struct{})}
+			// Blocks while the windows held ahead are not consumed.
+			select {
+			case queue <- w:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				w.err = ctx.Err()
+				close(w.done)
+				return
+			}
+			go func() {
+				defer func() { <-sem }()
+				st := time.Now()
+				w.blocks, w.err = p.fetch(ctx, w.from, w.to)
+				if w.err == nil {
+					metrics.BlocksFetchedTotal.Add(float64(len(w.blocks)))
+					metrics.PipelineBufferedBlocks.Add(float64(len(w.blocks)))
+					metrics.PipelineFetchDuration.Observe(time.Since(st).Seconds())
+				}
+				close(w.done)
+			}()
+		}
+	}()
+}
+
+// stop cancels the running fetches and waits for the workers to exit.
+func (p *pipeline) stop() {
+	if p.queue == nil {
+		return
+	}
+	p.cancel()
+	if p.current != nil {
+		p.release(p.current, p.next)
+	}
+	for w := range p.queue {
+		p.release(w, w.from)
+	}
+	p.queue, p.current = nil, nil
+}
+
+// release waits for w to be done and takes its blocks from consumed on off
+// the buffered blocks gauge.
+func (p *pipeline) release(w *fetchWindow, consumed int64) {
+	<-w.done
+	if w.err == nil {
+		metrics.PipelineBufferedBlocks.Sub(float64(w.to - max(consumed, w.from) + 1))
+	}
+}
+
+// fetchBlocks fetches blocks from to to with their logs, and their receipts
+// and internal transfers when those are indexed.
+func (i *Indexer) fetchBlocks(ctx context.Context, from, to int64) ([]fetchedBlock, error) {
+	rangeCtx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	blocks, err := i.fetcher.GetBlocksInRange(rangeCtx, uint64(from), uint64(to))
+	cancel()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch blocks %d-%d: %w", from, to, err)
+	}
+	if len(blocks) != int(to-from+1) {
+		return nil, fmt.Errorf("expected %d blocks from %d, got %d", to-from+1, from, len(blocks))
+	}
+
+	fetched := make([]fetchedBlock, len(blocks))
+	for n, b := range blocks {
+		num := from + int64(n)
+		fetched[n] = fetchedBlock{header: b.Header, logs: b.Logs}
+		// Receipts and traces are pinned to the block hash, a reorg makes them
+		// fail with ErrUnknownBlockHash rather than mismatch the header.
+		if i.receipts {
+			callCtx, cancel := context.WithTimeout(ctx, fetchTimeout)
+			fetched[n].receipts, err = i.fetcher.GetBlockReceipts(callCtx, b.Header.Hash())
+			cancel()
+			if err != nil {
+				return nil, fmt.Errorf("failed to fetch receipts for block %d: %w", num, err)
+			}
+		}
+		if i.internalTransfers {
+			callCtx, cancel := context.WithTimeout(ctx, fetchTimeout)
+			fetched[n].internalTransfers, err = i.fetcher.GetInternalTransfers(callCtx, b.Header.Hash())
+			cancel()
+			if err != nil {
+				return nil, fmt.Errorf("failed to trace block %d: %w", num, err)
+			}
+		}
+	}
+	return fetched, nil
+}
@@ -0,0 +1,109 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// fakeFetch fetches headers numbered like the blocks, counting concurrent and
+// total fetches of every window, and failing windows in fail once.
+type fakeFetch struct {
+	mu       sync.Mutex
+	inFlight int
+	maxIn    int
+	fetches  map[int64]int
+	fail     map[int64]bool
+}
+
+func (f *fakeFetch) fetch(ctx context.Context, from, to int64) ([]fetchedBlock, error) {
+	f.mu.Lock()
+	f.inFlight++
+	f.maxIn = max(f.maxIn, f.inFlight)
+	f.fetches[from]++
+	fail := f.fail[from]
+	delete(f.fail, from)
+	f.mu.Unlock()
+	defer func() {
+		f.mu.Lock()
+		f.inFlight--
+		f.mu.Unlock()
+	}()
+
+	select {
+	case <-time.After(time.Millisecond):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if fail {
+		return nil, errors.New("request timeout")
+	}
+	var blocks []fetchedBlock
+	for n := from; n <= to; n++ {
+		blocks = append(blocks, fetchedBlock{header: &types.Header{Number: big.NewInt(n)}})
+	}
+	return blocks, nil
+}
+
+func TestPipelineOrder(t *testing.T) {
+	f := &fakeFetch{fetches: make(map[int64]int), fail: map[int64]bool{21: true}}
+	p := newPipeline(f.fetch, 10, 3, 95)
+	defer p.stop()
+	ctx := context.Background()
+
+	rewound := false
+	for num := int64(1); num <= 95; num++ {
+		b, err := p.block(ctx, num)
+		if num == 21 && err == nil {
+			t.Fatal("expected the failing window to fail")
+		}
+		if err != nil {
+			// Asking again refetches the window.
+			if b, err = p.block(ctx, num); err != nil {
+				t.Fatalf("block %d failed again: %v", num, err)
+			}
+		}
+		if got := b.header.Number.Int64(); got != num {
+			t.Fatalf("expected block %d, got %d", num, got)
+		}
+		if num == 50 && !rewound {
+			// Going back, as after a reorg, restarts the pipeline there and
+			// refetches the windows already fetched.
+			rewound = true
+			num = 30
+		}
+	}
+	if f.maxIn > 3 {
+		t.Errorf("expected at most 3 concurrent fetches, got %d", f.maxIn)
+	}
+	if f.maxIn < 2 {
+		t.Errorf("expected windows to be fetched concurrently, got %d at most", f.maxIn)
+	}
+	if f.fetches[21] != 2 || f.fetches[31] < 2 || f.fetches[91] != 1 {
+		t.Errorf("unexpected fetches per window %v", f.fetches)
+	}
+}
+
+func TestPipelineStopWaitsForWorkers(t *testing.T) {
+	f := &fakeFetch{fetches: make(map[int64]int)}
+	p := newPipeline(f.fetch, 10, 4, 1_000_000)
+	if _, err := p.block(context.Background(), 1); err != nil {
+		t.Fatalf("Failed to get block: %v", err)
+	}
+	p.stop()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.inFlight != 0 {
+		t.Errorf("expected no fetch in flight after stop, got %d", f.inFlight)
+	}
+	// The look-ahead is bounded: the window being read, a full queue and
+	// at most one more the scheduler was about to queue.
+	if len(f.fetches) > 4+2 {
+		t.Errorf("expected at most 6 windows fetched, got %d", len(f.fetches))
+	}
+}
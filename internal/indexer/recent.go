@@ -0,0 +1,59 @@
+package indexer
+
+import (
+	"github.com/KhanSufiyanMirza/evm-indexer-go/internal/metrics"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// defaultRecentBlocks is how many of the last canonical blocks are kept in
+// memory, comfortably more than any reorg seen on mainnet.
+const defaultRecentBlocks = 256
+
+type recentBlock struct {
+	number     int64
+	hash       common.Hash
+	parentHash common.Hash
+	valid      bool
+}
+
+// recentBlocks is a ring of the last canonical blocks saved to the database,
+// indexed by number modulo its size. It mirrors the blocks table, so it must
+// be updated whenever the indexer saves a block or rolls back a reorg.
+type recentBlocks struct {
+	ring []recentBlock
+}
+
+func newRecentBlocks(size int) *recentBlocks {
+	return &recentBlocks{ring: make([]recentBlock, max(size, 1))}
+}
+
+// get returns the cached block number, if it is still in the ring.
+func (r *recentBlocks) get(number int64) (recentBlock, bool) {
+	if number < 0 {
+		return recentBlock{}, false
+	}
+	b := r.ring[number%int64(len(r.ring))]
+	if !b.valid || b.number != number {
+		metrics.RecentBlockCacheRequestsTotal.WithLabelValues("miss").Inc()
+		return recentBlock{}, false
+	}
+	metrics.RecentBlockCacheRequestsTotal.WithLabelValues("hit").Inc()
+	return b, true
+}
+
+// add caches block number, replacing whatever was cached for it.
+func (r *recentBlocks) add(number int64, hash, parentHash common.Hash) {
+	if number < 0 {
+		return
+	}
+	r.ring[number%int64(len(r.ring))] = recentBlock{number: number, hash: hash, parentHash: parentHash, valid: true}
+}
+
+// truncate drops every block above number, as a rollback does in the database.
+func (r *recentBlocks) truncate(number int64) {
+	for i, b := range r.ring {
+		if b.valid && b.number > number {
+			r.ring[i] = recentBlock{}
+		}
+	}
+}
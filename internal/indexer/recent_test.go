@@ -0,0 +1,38 @@
+package indexer
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestRecentBlocks(t *testing.T) {
+	r := newRecentBlocks(4)
+	hash := func(n int64) common.Hash { return common.BigToHash(big.NewInt(n + 1)) }
+	for n := int64(0); n < 6; n++ {
+		r.add(n, hash(n), hash(n-1))
+	}
+
+	// Blocks 0 and 1 were overwritten by 4 and 5.
+	for n := int64(0); n < 6; n++ {
+		b, ok := r.get(n)
+		if want := n >= 2; ok != want {
+			t.Fatalf("block %d: expected cached=%v, got %v", n, want, ok)
+		}
+		if ok && (b.hash != hash(n) || b.parentHash != hash(n-1)) {
+			t.Errorf("block %d: unexpected entry %+v", n, b)
+		}
+	}
+
+	r.truncate(3)
+	if _, ok := r.get(4); ok {
+		t.Error("expected block 4 to be dropped by truncate")
+	}
+	if _, ok := r.get(3); !ok {
+		t.Error("expected block 3 to survive truncate")
+	}
+	if _, ok := r.get(-1); ok {
+		t.Error("expected no block -1")
+	}
+}
@@ -0,0 +1,118 @@
+package indexer
+
+import (
+	"context"
+	"flag"
+	"os"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/KhanSufiyanMirza/evm-indexer-go/internal/gateway"
+	"github.com/KhanSufiyanMirza/evm-indexer-go/internal/testchain"
+)
+
+var recordFixtures = flag.Bool("record", false, "record the fixtures in testdata/replay from a simulated chain")
+
+// The fixtures are a canonical chain and a fork replacing its last
+// replayForkDepth blocks with one more, loaded on top of it to replay a reorg.
+const (
+	replayFixtures    = "testdata/replay"
+	canonicalFixtures = replayFixtures + "/canonical"
+	forkFixtures      = replayFixtures + "/fork"
+	replayForkDepth   = 3
+)
+
+var recordReplayOnce sync.Once
+
+// replayFetcher returns a fetcher replaying the canonical chain of
+// testdata/replay, record it with
+//
+//	go test ./internal/indexer -run Replay -record
+func replayFetcher(t *testing.T) *gateway.ReplayFetcher {
+	t.Helper()
+	if *recordFixtures {
+		recordReplayOnce.Do(func() { recordReplay(t) })
+	}
+	fetcher, err := gateway.NewReplayFetcher(canonicalFixtures)
+	if err != nil {
+		t.Fatalf("Failed to load fixtures, run with -record to record them: %v", err)
+	}
+	return fetcher
+}
+
+// recordReplay replaces testdata/replay with the blocks of a simulated chain
+// transferring tokens in every block, as the indexer fetches them, before and
+// after a reorg.
+func recordReplay(t *testing.T) {
+	t.Helper()
+	if err := os.RemoveAll(replayFixtures); err != nil {
+		t.Fatalf("Failed to remove old fixtures: %v", err)
+	}
+	chain := testchain.New(t)
+	for i := range 10 {
+		chain.Transfer(alice, int64(i+1))
+		chain.Commit()
+	}
+	head := record(t, chain, canonicalFixtures, 1)
+
+	// The dropped transfers are mined again on the new branch, next to new ones.
+	chain.Fork(int64(head) - replayForkDepth)
+	for range replayForkDepth + 1 {
+		chain.Transfer(bob, 100)
+		chain.Commit()
+	}
+	record(t, chain, forkFixtures, head-replayForkDepth+1)
+}
+
+// record saves the head of chain and its blocks from start on into dir.
+func record(t *testing.T, chain *testchain.Chain, dir string, start uint64) uint64 {
+	t.Helper()
+	fetcher, err := gateway.NewRecordingFetcher(gateway.NewBlockFetcher(chain.Client), dir)
+	if err != nil {
+		t.Fatalf("Failed to create recording fetcher: %v", err)
+	}
+	head, err := fetcher.GetBlockNumber(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to record the head: %v", err)
+	}
+	if _, err := fetcher.GetBlocksInRange(context.Background(), start, head); err != nil {
+		t.Fatalf("Failed to record blocks %d-%d: %v", start, head, err)
+	}
+	return head
+}
+
+func TestRunReplaysReorg(t *testing.T) {
+	fetcher := replayFetcher(t)
+	store, pool := testStore(t)
+	ctx := context.Background()
+	head, err := fetcher.GetBlockNumber(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx := NewIndexer(fetcher, store)
+	runIndexer(t, idx, 1, int64(head))
+	assertReplayed(t, fetcher, pool)
+
+	headers, err := fetcher.GetHeadersInRange(ctx, head-replayForkDepth+1, head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var replaced []string
+	for _, h := range headers {
+		replaced = append(replaced, h.Hash().String())
+	}
+	if err := fetcher.Load(forkFixtures); err != nil {
+		t.Fatalf("Failed to load the fork: %v", err)
+	}
+	newHead, err := fetcher.GetBlockNumber(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runIndexer(t, idx, int64(head)+1, int64(newHead))
+	assertReplayed(t, fetcher, pool)
+	got := queryStrings(t, pool, "SELECT hash FROM blocks WHERE NOT is_canonical ORDER BY number")
+	if !slices.Equal(got, replaced) {
+		t.Errorf("expected the replaced blocks %v to be kept as non-canonical, got %v", replaced, got)
+	}
+}
@@ -2,6 +2,7 @@ package indexer
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -11,23 +12,134 @@ import (
 	"github.com/KhanSufiyanMirza/evm-indexer-go/internal/gateway"
 	"github.com/KhanSufiyanMirza/evm-indexer-go/internal/metrics"
 	"github.com/KhanSufiyanMirza/evm-indexer-go/internal/storage"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type Indexer struct {
-	fetcher gateway.BlockFetcher
-	store   *storage.Store
+	fetcher           gateway.BlockFetcher
+	store             *storage.Store
+	receipts          bool
+	internalTransfers bool
+	recent            *recentBlocks
+	seeded            bool
+	finalizerInterval time.Duration
+	fetchWorkers      int
+	backfillRange     int64
+	backfillDepth     uint64
+	registry          *Registry
 }
 
-func NewIndexer(fetcher gateway.BlockFetcher, store *storage.Store) *Indexer {
-	return &Indexer{
-		fetcher: fetcher,
-		store:   store,
+// Option configures an Indexer.
+type Option func(*Indexer)
+
+// WithReceipts also fetches and stores the receipt of every transaction, so
+// status, gas used and fees are queryable. It costs one eth_getBlockReceipts
+// call per block, and is what lets log verification detect missing logs.
+func WithReceipts() Option {
+	return func(i *Indexer) {
+		i.receipts = true
+	}
+}
+
+// WithInternalTransfers also traces every block and stores the native value
+// moved by calls inside transactions. It needs an endpoint exposing
+// debug_traceBlockByHash, one of the most expensive RPC methods.
+func WithInternalTransfers() Option {
+	return func(i *Indexer) {
+		i.internalTransfers = true
+	}
+}
+
+// WithRegistry sets the event handlers Run indexes logs with, instead of
+// DefaultRegistry. The fetcher must query the logs of all of them, see
+// Registry.Filter.
+func WithRegistry(r *Registry) Option {
+	return func(i *Indexer) {
+		i.registry = r
 	}
 }
 
+// WithFetchWorkers sets how many windows of blocks are fetched concurrently
+// ahead of the block being saved. Values below 1 are ignored.
+func WithFetchWorkers(n int) Option {
+	return func(i *Indexer) {
+		if n > 0 {
+			i.fetchWorkers = n
+		}
+	}
+}
+
+// WithBackfill indexes blocks the chain finalized in ranges of rangeSize
+// blocks, with one log query per range and one transaction saving the whole
+// range, before switching to per-block ingestion near the tip. On chains
+// whose nodes do not serve the "finalized" block tag, blocks safeBlockDepth
+// below the tip count as finalized. It has no effect when receipts or
+// internal transfers are indexed, those are fetched per block anyway. Values
+// of rangeSize below 1 are ignored.
+func WithBackfill(rangeSize int, safeBlockDepth uint64) Option {
+	return func(i *Indexer) {
+		if rangeSize > 0 {
+			i.backfillRange = int64(rangeSize)
+			i.backfillDepth = safeBlockDepth
+		}
+	}
+}
+
+// WithRecentBlocks sets how many of the last canonical blocks are kept in
+// memory for the parent check and the reorg ancestor search. Values below 1
+// are ignored.
+func WithRecentBlocks(n int) Option {
+	return func(i *Indexer) {
+		if n > 0 {
+			i.recent = newRecentBlocks(n)
+		}
+	}
+}
+
+func NewIndexer(fetcher gateway.BlockFetcher, store *storage.Store, opts ...Option) *Indexer {
+	i := &Indexer{
+		fetcher:           fetcher,
+		store:             store,
+		recent:            newRecentBlocks(defaultRecentBlocks),
+		finalizerInterval: defaultFinalizerInterval,
+		fetchWorkers:      defaultFetchWorkers,
+		registry:          DefaultRegistry(),
+	}
+	for _, opt := range opts {
+		opt(i)
+	}
+	return i
+}
+
+const (
+	// prefetchWindow is how many blocks a fetch worker gets in one GetBlocksInRange call.
+	prefetchWindow = 100
+	// maxRefetches bounds how often a window is refetched in a row because a
+	// block was reorged out while its logs were being fetched, or its logs
+	// failed verification.
+	maxRefetches = 3
+	// defaultFinalizerInterval is how often RunFinalizer checks finality, about one block.
+	defaultFinalizerInterval = 12 * time.Second
+)
+
 func (i *Indexer) Run(ctx context.Context, startBlock, endBlock int64) (int64, error) {
 	lastProcessedBlock := startBlock - 1
+	refetches, parentMismatches := 0, 0
+	if !i.seeded {
+		i.seedRecentBlocks(ctx)
+	}
+	if i.backfillRange > 0 && !i.receipts && !i.internalTransfers && endBlock-startBlock+1 >= i.backfillRange {
+		backfilled, err := i.backfill(ctx, startBlock, endBlock)
+		if err != nil || ctx.Err() != nil {
+			return backfilled, err
+		}
+		lastProcessedBlock, startBlock = backfilled, backfilled+1
+	}
+	blocks := newPipeline(i.fetchBlocks, prefetchWindow, i.fetchWorkers, endBlock)
+	defer blocks.stop()
 
 	for num := startBlock; num <= endBlock; num++ {
 		select {
@@ -43,32 +155,57 @@ func (i *Indexer) Run(ctx context.Context, startBlock, endBlock int64) (int64, e
 		// Start timing block processing duration
 		startTimer := time.Now()
 
-		previousBlock, err := i.store.GetBlockByNumber(opCtx, num-1)
-		isFirstRun := false
+		previousHash, found, err := i.savedBlockHash(opCtx, num-1)
 		if err != nil {
-			if errors.Is(err, storage.ErrBlockNotFound) {
-				isFirstRun = true
-			} else {
-				slog.Error("Failed to get previous block", "block", num-1, "error", err, "type", "db_fatal")
-				cancel()
-				return lastProcessedBlock, fmt.Errorf("fatal db error getting previous block %d: %w", num-1, err)
-			}
+			slog.Error("Failed to get previous block", "block", num-1, "error", err, "type", "db_fatal")
+			cancel()
+			return lastProcessedBlock, fmt.Errorf("fatal db error getting previous block %d: %w", num-1, err)
 		}
+		isFirstRun := !found
 
-		// 1. Fetch
-		block, err := i.fetcher.Fetch(opCtx, uint64(num))
+		// 1. Fetch (the block, its ERC20 transfers, receipts and traces come from the pipeline)
+		fetched, err := blocks.block(ctx, num)
+		if ctx.Err() != nil {
+			// Shutting down while waiting for the block.
+			cancel()
+			return lastProcessedBlock, nil
+		}
+		if errors.Is(err, gateway.ErrUnknownBlockHash) && refetches < maxRefetches {
+			// The chain moved under us between the header and its logs or
+			// receipts, start over so they all come from the same fork.
+			refetches++
+			slog.Warn("Block reorged out while fetching its logs or receipts, refetching", "block", num, "attempt", refetches, "error", err)
+			cancel()
+			num--
+			continue
+		}
+		if pauseWhileCircuitOpen(ctx, num, err) {
+			cancel()
+			num--
+			continue
+		}
 		if err != nil {
 			slog.Error("Failed to fetch block", "block", num, "error", err)
 			cancel()
 			return lastProcessedBlock, fmt.Errorf("failed to fetch block %d: %w", num, err)
 		}
+		refetches = 0
+		block, logs := fetched.header, fetched.logs
+		receipts, internalTransfers := fetched.receipts, fetched.internalTransfers
 
-		if !isFirstRun && previousBlock.Hash != block.ParentHash().String() {
+		if !isFirstRun && previousHash != block.ParentHash {
 			metrics.ReorgDetectedTotal.Inc()
-			slog.Warn("Reorg detected", "block", num, "dbHash", previousBlock.Hash, "parentHash", block.ParentHash().String())
+			slog.Warn("Reorg detected", "block", num, "dbHash", previousHash.String(), "parentHash", block.ParentHash.String())
+			// Resuming from the ancestor restarts the pipeline, which drops
+			// everything it fetched from this fork.
 
 			// 1. Find Common Ancestor
-			ancestorBlockNumber, err := i.findCommonAncestor(opCtx, num-1)
+			ancestorBlockNumber, err := i.findCommonAncestor(opCtx, num-1, block.ParentHash)
+			if pauseWhileCircuitOpen(ctx, num, err) {
+				cancel()
+				num--
+				continue
+			}
 			if err != nil {
 				cancel()
 				return lastProcessedBlock, fmt.Errorf("failed to find common ancestor: %w", err)
@@ -82,6 +219,7 @@ func (i *Indexer) Run(ctx context.Context, startBlock, endBlock int64) (int64, e
 				cancel()
 				return lastProcessedBlock, fmt.Errorf("fatal db error rolling back from block %d: %w", ancestorBlockNumber, err)
 			}
+			i.recent.truncate(ancestorBlockNumber)
 			reorgDepth := num - ancestorBlockNumber
 			slog.Info("Rolled back data above block", "block", ancestorBlockNumber)
 
@@ -98,67 +236,84 @@ func (i *Indexer) Run(ctx context.Context, startBlock, endBlock int64) (int64, e
 			cancel()
 			continue
 		}
-		// 2. Insert Block
-		// Note: CreateBlock uses ON CONFLICT DO UPDATE is_canonical = TRUE and reorg_detected_at = NULL.
-		// If it exists, we get pgx.ErrNoRows (handled by store.SaveBlock).
-		// This is idempotent.
-		err = i.store.SaveBlock(opCtx, sqlc.CreateBlockParams{
-			Hash:       block.Hash().String(),
-			Number:     block.Number().Int64(),
-			ParentHash: block.ParentHash().String(),
-			Timestamp:  time.Unix(int64(block.Time()), 0),
-		})
-		if err != nil {
-			slog.Error("Failed to save block", "block", num, "error", err, "type", "db_fatal")
+		// Check the logs (and receipts) against the header before anything is saved,
+		// logs of another block or a fork must not get the block marked processed.
+		// Only with receipts are partial logs caught too, the header alone
+		// cannot tell that a log is missing.
+		if err := gateway.VerifyBlockLogs(block, logs, receipts, i.registry.Filters()); err != nil {
+			var mismatch *gateway.LogsMismatchError
+			if errors.As(err, &mismatch) {
+				metrics.LogVerificationFailuresTotal.WithLabelValues(mismatch.Reason).Inc()
+			}
+			slog.Error("ALERT: Block logs failed verification", "block", num, "hash", block.Hash().String(), "error", err, "attempt", refetches+1)
+			if refetches < maxRefetches {
+				refetches++
+				cancel()
+				num--
+				continue
+			}
 			cancel()
-			return lastProcessedBlock, fmt.Errorf("fatal db error saving block %d: %w", num, err)
+			return lastProcessedBlock, fmt.Errorf("logs of block %d failed verification: %w", num, err)
 		}
 
-		// 3. Insert ERC20 Transfers (batch)
-		erc20Transfers, err := i.fetcher.GetERC20TransfersInRange(opCtx, block.NumberU64(), block.NumberU64())
+		// 2. Decode the logs with the event handlers, then save the block, the
+		// handlers' rows, receipts and internal transfers and mark it processed,
+		// in one transaction.
+		// Inserts use ON CONFLICT DO UPDATE for idempotency, re-canonicalizing the rows:
+		// a transaction re-mined after a reorg keeps its hash but may land in another block.
+		events, err := i.registry.decode(logs)
 		if err != nil {
-			slog.Error("Failed to get ERC20 transfers", "block", num, "error", err)
 			cancel()
-			return lastProcessedBlock, fmt.Errorf("failed to get ERC20 transfers for block %d: %w", num, err)
+			return lastProcessedBlock, fmt.Errorf("failed to decode logs of block %d: %w", num, err)
 		}
-		batchParams := make([]sqlc.BatchCreateERC20TransferParams, 0, len(erc20Transfers))
-		for _, transferLog := range erc20Transfers {
-			from, to, value, ok := gateway.DecodeERC20TransferLog(transferLog)
-			if !ok {
-				// NOTE: ERC721 Transfer event has 4 topics and ERC20 Transfer event has 3 topics both have same signature
-				// so we can't differentiate between them just by signature
-				continue
+		indexed := storage.IndexedBlock{
+			Block: sqlc.CreateBlockParams{
+				Hash:       block.Hash().String(),
+				Number:     block.Number.Int64(),
+				ParentHash: block.ParentHash.String(),
+				Timestamp:  time.Unix(int64(block.Time), 0),
+			},
+			Events: events,
+		}
+		if i.receipts {
+			indexed.Receipts, err = receiptBatchParams(num, receipts)
+			if err != nil {
+				cancel()
+				return lastProcessedBlock, fmt.Errorf("failed to encode receipts for block %d: %w", num, err)
 			}
-			batchParams = append(batchParams, sqlc.BatchCreateERC20TransferParams{
-				TxHash:       transferLog.TxHash.String(),
-				LogIndex:     int32(transferLog.Index),
-				BlockNumber:  int64(num),
-				FromAddress:  from.Hex(),
-				ToAddress:    to.Hex(),
-				Value:        pgtype.Numeric{Int: value, Valid: true},
-				TokenAddress: transferLog.Address.Hex(),
-			})
 		}
-		// Batch insert uses ON CONFLICT DO UPDATE is_canonical = TRUE and reorg_detected_at = NULL for idempotency.
-		err = i.store.SaveERC20TransferBatch(opCtx, batchParams)
-		if err != nil {
-			slog.Error("Failed to save ERC20 transfers", "block", num, "error", err, "type", "db_fatal")
+		if i.internalTransfers {
+			indexed.InternalTransfers = internalTransferBatchParams(num, internalTransfers)
+		}
+		err = i.store.SaveIndexedBlock(opCtx, indexed)
+		if errors.Is(err, storage.ErrParentMismatch) && parentMismatches < maxRefetches {
+			// The saved chain changed since the parent check, which read the
+			// recent block cache: check again against the database.
+			parentMismatches++
+			slog.Warn("Saved parent changed before the block was saved, checking again", "block", num, "error", err)
+			i.recent.truncate(num - 2)
 			cancel()
-			return lastProcessedBlock, fmt.Errorf("fatal db error saving ERC20 Transfers for block %d: %w", num, err)
+			num--
+			continue
 		}
-		slog.Info("Indexed ERC20 transfers", "block", num, "count", len(batchParams))
-
-		// 3. Mark Processed (Guard)
-		err = i.store.MarkBlockProcessed(opCtx, num)
 		if err != nil {
-			slog.Error("Failed to mark block as processed", "block", num, "error", err, "type", "db_fatal")
+			slog.Error("Failed to save block", "block", num, "error", err, "type", "db_fatal")
 			cancel()
-			return lastProcessedBlock, fmt.Errorf("fatal db error marking block %d as processed: %w", num, err)
+			return lastProcessedBlock, fmt.Errorf("fatal db error saving block %d: %w", num, err)
+		}
+		parentMismatches = 0
+		i.recent.add(num, block.Hash(), block.ParentHash)
+		i.observeEvents(num, events)
+		if i.receipts {
+			slog.Info("Indexed receipts", "block", num, "count", len(indexed.Receipts))
+		}
+		if i.internalTransfers {
+			slog.Info("Indexed internal transfers", "block", num, "count", len(indexed.InternalTransfers))
 		}
 
 		lastProcessedBlock = num
 
-		// 4. Update metrics and observability
+		// 3. Update metrics and observability
 		metrics.BlocksProcessedTotal.Inc()
 		metrics.CurrentBlockHeight.Set(float64(num))
 		metrics.BlockProcessingDuration.Observe(time.Since(startTimer).Seconds())
@@ -169,11 +324,84 @@ func (i *Indexer) Run(ctx context.Context, startBlock, endBlock int64) (int64, e
 	}
 	return lastProcessedBlock, nil
 }
+
+// pauseWhileCircuitOpen reports whether err is the RPC circuit breaker
+// rejecting calls, after waiting until it lets calls through again or ctx is
+// cancelled. The caller then retries the block instead of failing.
+func pauseWhileCircuitOpen(ctx context.Context, block int64, err error) bool {
+	var open *gateway.CircuitOpenError
+	if !errors.As(err, &open) {
+		return false
+	}
+	wait := time.Until(open.RetryAt)
+	slog.Warn("Indexer paused while the RPC circuit breaker is open", "block", block, "resumeIn", wait.Round(time.Millisecond))
+	select {
+	case <-ctx.Done():
+	case <-time.After(wait):
+	}
+	return true
+}
+
+// observeEvents logs and counts the rows each handler indexed for block num.
+func (i *Indexer) observeEvents(num int64, events []storage.EventRows) {
+	for n, rows := range events {
+		name := i.registry.handlers[n].Name()
+		metrics.IndexedEventsTotal.WithLabelValues(name).Add(float64(rows.Len()))
+		slog.Info("Indexed events", "block", num, "handler", name, "count", rows.Len())
+	}
+}
+
+// receiptBatchParams converts receipts to rows, with their logs kept as JSON.
+func receiptBatchParams(num int64, receipts types.Receipts) ([]sqlc.BatchCreateReceiptParams, error) {
+	params := make([]sqlc.BatchCreateReceiptParams, 0, len(receipts))
+	for _, r := range receipts {
+		logs, err := json.Marshal(r.Logs)
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, sqlc.BatchCreateReceiptParams{
+			TxHash:            r.TxHash.String(),
+			BlockNumber:       num,
+			BlockHash:         r.BlockHash.String(),
+			TxIndex:           int32(r.TransactionIndex),
+			TxType:            int16(r.Type),
+			Status:            int16(r.Status),
+			GasUsed:           int64(r.GasUsed),
+			CumulativeGasUsed: int64(r.CumulativeGasUsed),
+			EffectiveGasPrice: pgtype.Numeric{Int: r.EffectiveGasPrice, Valid: r.EffectiveGasPrice != nil},
+			ContractAddress:   pgtype.Text{String: r.ContractAddress.Hex(), Valid: r.ContractAddress != (common.Address{})},
+			Logs:              logs,
+		})
+	}
+	return params, nil
+}
+
+// internalTransferBatchParams converts traced internal transfers to rows.
+func internalTransferBatchParams(num int64, transfers []gateway.InternalTransfer) []sqlc.BatchCreateInternalTransferParams {
+	params := make([]sqlc.BatchCreateInternalTransferParams, 0, len(transfers))
+	for _, t := range transfers {
+		params = append(params, sqlc.BatchCreateInternalTransferParams{
+			TxHash:       t.TxHash.String(),
+			TraceAddress: t.TracePath(),
+			BlockNumber:  num,
+			CallType:     t.CallType,
+			FromAddress:  t.From.Hex(),
+			ToAddress:    t.To.Hex(),
+			Value:        pgtype.Numeric{Int: t.Value, Valid: true},
+		})
+	}
+	return params
+}
+
+// RunFinalizer periodically marks blocks SAFE and FINALIZED following the
+// node's "safe" and "finalized" block tags. On chains whose nodes do not serve
+// the tags, blocks safeBlockDepth below the tip are marked FINALIZED instead.
 func (i *Indexer) RunFinalizer(ctx context.Context, safeBlockDepth uint64) error {
-	ticker := time.NewTicker(time.Second * 12)
+	ticker := time.NewTicker(i.finalizerInterval)
 	defer ticker.Stop()
 
-	var lastFinalizedBlock int64
+	var lastFinalizedBlock, lastSafeBlock int64
+	useTags := true
 
 	for {
 		select {
@@ -182,13 +410,37 @@ func (i *Indexer) RunFinalizer(ctx context.Context, safeBlockDepth uint64) error
 			return nil
 		case <-ticker.C:
 			opCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-			blockNumber, err := i.fetcher.GetBlockNumberWithRetry(opCtx)
+			blockNumber, err := i.fetcher.GetBlockNumber(opCtx)
 			if err != nil {
 				slog.Error("Failed to get block number", "error", err)
 				cancel()
 				continue
 			}
 			finalizableHeight := int64(blockNumber) - int64(safeBlockDepth)
+			if useTags {
+				finalized, safe, err := i.finalityTags(opCtx)
+				switch {
+				case errors.Is(err, gateway.ErrBlockTagUnsupported):
+					slog.Warn("Node does not serve safe/finalized block tags, finalizing by depth", "depth", safeBlockDepth, "error", err)
+					useTags = false
+				case err != nil:
+					slog.Error("Failed to get finalized block", "error", err)
+					cancel()
+					continue
+				default:
+					finalizableHeight = finalized
+					if safe > lastSafeBlock {
+						err = i.store.MarkBlockSafe(opCtx, safe)
+						if err != nil {
+							slog.Error("Failed to mark block as safe", "safeHeight", safe, "error", err, "type", "db_fatal")
+							cancel()
+							continue
+						}
+						slog.Info("Marked blocks safe", "upTo", safe)
+						lastSafeBlock = safe
+					}
+				}
+			}
 			if finalizableHeight <= 0 || finalizableHeight <= lastFinalizedBlock {
 				cancel()
 				continue
@@ -220,13 +472,63 @@ func (i *Indexer) RunFinalizer(ctx context.Context, safeBlockDepth uint64) error
 	}
 }
 
-// findCommonAncestor steps back from startBlock verifying checks against canonical chain
-// Returns the block number of the first block that matches (Common Ancestor).
-func (i *Indexer) findCommonAncestor(ctx context.Context, startBlock int64) (int64, error) {
-	// We start from the block we *thought* was the tip (startBlock) and go backwards.
-	// Since we called this, we know startBlock is likely invalid (or at least its successor didn't match it).
-	// But it's safer to check startBlock again against canonical to be sure, and then descend.
+// seedRecentBlocks fills the recent block cache with the highest canonical
+// blocks in the database. Failing to do so only costs database lookups later.
+func (i *Indexer) seedRecentBlocks(ctx context.Context) {
+	blocks, err := i.store.ListRecentBlocks(ctx, int32(len(i.recent.ring)))
+	if err != nil {
+		slog.Warn("Failed to seed recent block cache, falling back to the database", "error", err)
+		return
+	}
+	for _, b := range blocks {
+		i.recent.add(b.Number, common.HexToHash(b.Hash), common.HexToHash(b.ParentHash))
+	}
+	i.seeded = true
+	slog.Info("Seeded recent block cache", "blocks", len(blocks))
+}
+
+// savedBlockHash returns the hash of the canonical block number saved in the
+// database, looked up in the recent block cache first. found is false when no
+// such block was saved.
+func (i *Indexer) savedBlockHash(ctx context.Context, number int64) (hash common.Hash, found bool, err error) {
+	if b, ok := i.recent.get(number); ok {
+		return b.hash, true, nil
+	}
+	dbBlock, err := i.store.GetBlockByNumber(ctx, number)
+	if errors.Is(err, storage.ErrBlockNotFound) {
+		return common.Hash{}, false, nil
+	}
+	if err != nil {
+		return common.Hash{}, false, err
+	}
+	hash = common.HexToHash(dbBlock.Hash)
+	i.recent.add(number, hash, common.HexToHash(dbBlock.ParentHash))
+	return hash, true, nil
+}
 
+// finalityTags returns the node's finalized and safe block numbers. A node
+// serving only the finalized tag gets it used for both.
+func (i *Indexer) finalityTags(ctx context.Context) (finalized, safe int64, err error) {
+	f, err := i.fetcher.GetTaggedBlockNumber(ctx, rpc.FinalizedBlockNumber)
+	if err != nil {
+		return 0, 0, err
+	}
+	s, err := i.fetcher.GetTaggedBlockNumber(ctx, rpc.SafeBlockNumber)
+	if errors.Is(err, gateway.ErrBlockTagUnsupported) {
+		s = f
+	} else if err != nil {
+		return 0, 0, err
+	}
+	return int64(f), int64(max(s, f)), nil
+}
+
+// findCommonAncestor steps back from startBlock, whose canonical hash is
+// canonicalHash, comparing the saved blocks against the canonical chain.
+// Returns the block number of the first block that matches (Common Ancestor).
+// Saved hashes come from the recent block cache, and the canonical chain is
+// followed through parent hashes, so only one header per mismatching block is
+// fetched.
+func (i *Indexer) findCommonAncestor(ctx context.Context, startBlock int64, canonicalHash common.Hash) (int64, error) {
 	// Safety limit to prevent infinite loops (though usually 0 is the floor)
 	const maxReorgDepth = 1000
 
@@ -238,32 +540,38 @@ func (i *Indexer) findCommonAncestor(ctx context.Context, startBlock int64) (int
 			return 0, fmt.Errorf("reorg depth exceeded safe limit of %d blocks", maxReorgDepth)
 		}
 
-		// 1. Get canonical block
-		canonicalBlock, err := i.fetcher.Fetch(ctx, uint64(current))
+		// 1. Get local block
+		localHash, found, err := i.savedBlockHash(ctx, current)
 		if err != nil {
-			return 0, fmt.Errorf("failed to fetch canonical block %d: %w", current, err)
-		}
-
-		// 2. Get local block
-		dbBlock, err := i.store.GetBlockByNumber(ctx, current)
-		if err != nil {
-			// If we don't have this block, it can't be an ancestor?
-			// Or maybe we haven't indexed it yet?
-			// But we are walking BACK from a block we presumably have.
-			// If we fail to get it, it's a critical error.
 			return 0, fmt.Errorf("failed to get db block %d: %w", current, err)
 		}
+		if !found {
+			// We are walking back from a block we saved, a gap is a critical error.
+			return 0, fmt.Errorf("failed to get db block %d: %w", current, storage.ErrBlockNotFound)
+		}
 
-		// 3. Compare
-		if canonicalBlock.Hash().String() == dbBlock.Hash {
+		// 2. Compare
+		if localHash == canonicalHash {
 			// Match found! This is the common ancestor.
 			return current, nil
 		}
 
 		// Mismatch, keep going back
-		slog.Warn("Block hash mismatch", "block", current, "canonical", canonicalBlock.Hash().String(), "db", dbBlock.Hash)
-		current--
+		slog.Warn("Block hash mismatch", "block", current, "canonical", canonicalHash.String(), "db", localHash.String())
+
+		// 3. Get the canonical parent hash (only the header is needed)
+		canonicalBlock, err := i.fetcher.FetchHeader(ctx, uint64(current))
+		if err != nil {
+			return 0, fmt.Errorf("failed to fetch canonical block header %d: %w", current, err)
+		}
 		depth++
+		if canonicalBlock.Hash() != canonicalHash {
+			// The chain reorged again during the search, compare against the new block.
+			canonicalHash = canonicalBlock.Hash()
+			continue
+		}
+		canonicalHash = canonicalBlock.ParentHash
+		current--
 	}
 
 	return 0, fmt.Errorf("no common ancestor found down to block 0")
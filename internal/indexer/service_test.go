@@ -0,0 +1,586 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KhanSufiyanMirza/evm-indexer-go/db/sqlc"
+	"github.com/KhanSufiyanMirza/evm-indexer-go/internal/gateway"
+	"github.com/KhanSufiyanMirza/evm-indexer-go/internal/storage"
+	"github.com/KhanSufiyanMirza/evm-indexer-go/internal/testchain"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	alice = common.HexToAddress("0xa11ce")
+	bob   = common.HexToAddress("0xb0b")
+)
+
+// testStore returns a store on a fresh schema with all migrations applied, in
+// the database configured like the server's (RDB_HOST, RDB_USER, ...). The
+// test is skipped when no database is configured, `make test-integration`
+// starts one and runs the tests against it.
+func testStore(t *testing.T) (*storage.Store, *pgxpool.Pool) {
+	t.Helper()
+	if os.Getenv(sqlc.RDB_HOST) == "" {
+		t.Skip("RDB_HOST not set, the integration tests need Postgres (make test-integration)")
+	}
+	config, err := sqlc.LoadRDBConfigFromEnv()
+	if err != nil {
+		t.Fatalf("Failed to load database config: %v", err)
+	}
+	dsn := fmt.Sprintf("postgresql://%s:%s@%s:%d/%s?sslmode=disable",
+		config.Username, config.Password, config.Host, config.Port, config.DBName)
+	ctx := context.Background()
+
+	admin, err := pgx.Connect(ctx, dsn)
+	if err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	schema := fmt.Sprintf("indexer_test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec(ctx, "CREATE SCHEMA "+schema); err != nil {
+		t.Fatalf("Failed to create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := admin.Exec(context.Background(), "DROP SCHEMA "+schema+" CASCADE"); err != nil {
+			t.Errorf("Failed to drop schema %s: %v", schema, err)
+		}
+		admin.Close(context.Background())
+	})
+
+	poolConfig, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		t.Fatalf("Failed to parse database config: %v", err)
+	}
+	poolConfig.ConnConfig.RuntimeParams["search_path"] = schema
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	migrations, err := filepath.Glob("../../db/migrations/*.up.sql")
+	if err != nil || len(migrations) == 0 {
+		t.Fatalf("No migrations found: %v", err)
+	}
+	for _, path := range migrations {
+		migration, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Failed to read migration: %v", err)
+		}
+		if _, err := pool.Exec(ctx, string(migration)); err != nil {
+			t.Fatalf("Failed to apply %s: %v", filepath.Base(path), err)
+		}
+	}
+	return storage.NewStore(sqlc.NewStoreFromPool(pool)), pool
+}
+
+// queryStrings returns the single text column of every row of query.
+func queryStrings(t *testing.T, pool *pgxpool.Pool, query string) []string {
+	t.Helper()
+	rows, err := pool.Query(context.Background(), query)
+	if err != nil {
+		t.Fatalf("Query %q failed: %v", query, err)
+	}
+	values, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		t.Fatalf("Query %q failed: %v", query, err)
+	}
+	return values
+}
+
+// runIndexer indexes blocks start to end and fails unless all of them were.
+func runIndexer(t *testing.T, idx *Indexer, start, end int64) {
+	t.Helper()
+	last, err := idx.Run(context.Background(), start, end)
+	if err != nil {
+		t.Fatalf("Run(%d, %d) failed: %v", start, end, err)
+	}
+	if last != end {
+		t.Fatalf("Run(%d, %d) stopped at block %d", start, end, last)
+	}
+}
+
+// assertCanonical checks that the canonical rows of blocks and erc20_transfers
+// are exactly the chain's blocks and Transfer logs, from block 1 to its head.
+func assertCanonical(t *testing.T, chain *testchain.Chain, pool *pgxpool.Pool) {
+	t.Helper()
+	head := chain.Head()
+
+	var headers []*types.Header
+	for n := int64(1); n <= head; n++ {
+		headers = append(headers, chain.Header(n))
+	}
+	logs, err := chain.Client.FilterLogs(context.Background(), ethereum.FilterQuery{
+		FromBlock: big.NewInt(1),
+		ToBlock:   big.NewInt(head),
+		Topics:    [][]common.Hash{{crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to get chain logs: %v", err)
+	}
+	assertRows(t, pool, headers, logs)
+}
+
+// assertRows checks that the canonical rows of blocks and erc20_transfers are
+// exactly headers, from block 1 on, and the Transfer logs.
+func assertRows(t *testing.T, pool *pgxpool.Pool, headers []*types.Header, logs []types.Log) {
+	t.Helper()
+	var wantBlocks []string
+	for _, h := range headers {
+		wantBlocks = append(wantBlocks, fmt.Sprintf("%d %s", h.Number, h.Hash()))
+	}
+	gotBlocks := queryStrings(t, pool, "SELECT number || ' ' || hash FROM blocks WHERE is_canonical ORDER BY number")
+	if !slices.Equal(gotBlocks, wantBlocks) {
+		t.Errorf("canonical blocks:\n got %v\nwant %v", gotBlocks, wantBlocks)
+	}
+
+	var wantTransfers []string
+	for _, l := range logs {
+		from, to, value, ok := gateway.DecodeERC20TransferLog(l)
+		if !ok {
+			t.Fatalf("Undecodable Transfer log %+v", l)
+		}
+		wantTransfers = append(wantTransfers, fmt.Sprintf("%d %d %s %s %s %s %s",
+			l.BlockNumber, l.Index, l.TxHash, from.Hex(), to.Hex(), value, l.Address.Hex()))
+	}
+	gotTransfers := queryStrings(t, pool, `SELECT concat_ws(' ', block_number, log_index, tx_hash, from_address, to_address, value, token_address)
+		FROM erc20_transfers WHERE is_canonical ORDER BY block_number, log_index`)
+	if !slices.Equal(gotTransfers, wantTransfers) {
+		t.Errorf("canonical transfers:\n got %v\nwant %v", gotTransfers, wantTransfers)
+	}
+}
+
+// assertReplayed is assertCanonical for the chain replayed by fetcher.
+func assertReplayed(t *testing.T, fetcher gateway.BlockFetcher, pool *pgxpool.Pool) {
+	t.Helper()
+	head, err := fetcher.GetBlockNumber(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to get replayed head: %v", err)
+	}
+	blocks, err := fetcher.GetBlocksInRange(context.Background(), 1, head)
+	if err != nil {
+		t.Fatalf("Failed to get replayed blocks: %v", err)
+	}
+	var headers []*types.Header
+	var logs []types.Log
+	for _, b := range blocks {
+		headers = append(headers, b.Header)
+		logs = append(logs, b.Logs...)
+	}
+	assertRows(t, pool, headers, logs)
+}
+
+func TestRunIndexesTransfers(t *testing.T) {
+	store, pool := testStore(t)
+	chain := testchain.New(t)
+	for i := range 5 {
+		chain.Transfer(alice, int64(i+1))
+		if i%2 == 0 {
+			chain.Transfer(bob, 10)
+		}
+		chain.Commit()
+	}
+	chain.Commit() // an empty block
+
+	idx := NewIndexer(gateway.NewBlockFetcher(chain.Client), store)
+	runIndexer(t, idx, 1, chain.Head())
+	assertCanonical(t, chain, pool)
+
+	// The mint, five transfers to alice and three to bob.
+	if got := queryStrings(t, pool, "SELECT count(*)::text FROM erc20_transfers"); got[0] != "9" {
+		t.Errorf("expected 9 transfers, got %s", got[0])
+	}
+	// Running again over the same blocks changes nothing.
+	runIndexer(t, idx, 1, chain.Head())
+	assertCanonical(t, chain, pool)
+}
+
+func TestRunHandlesReorgs(t *testing.T) {
+	for _, depth := range []int64{1, 3, 8} {
+		t.Run(fmt.Sprintf("depth=%d", depth), func(t *testing.T) {
+			store, pool := testStore(t)
+			chain := testchain.New(t)
+			for i := range 10 {
+				chain.Transfer(alice, int64(i+1))
+				chain.Commit()
+			}
+			idx := NewIndexer(gateway.NewBlockFetcher(chain.Client), store)
+			head := chain.Head()
+			runIndexer(t, idx, 1, head)
+
+			ancestor := head - depth
+			var replaced []string
+			for n := ancestor + 1; n <= head; n++ {
+				replaced = append(replaced, chain.Header(n).Hash().String())
+			}
+			// The dropped transfers are mined again on the new branch, next
+			// to new ones, and the branch is one block longer.
+			chain.Fork(ancestor)
+			for range depth + 1 {
+				chain.Transfer(bob, 100)
+				chain.Commit()
+			}
+
+			runIndexer(t, idx, head+1, chain.Head())
+			assertCanonical(t, chain, pool)
+			got := queryStrings(t, pool, "SELECT hash FROM blocks WHERE NOT is_canonical ORDER BY number")
+			if !slices.Equal(got, replaced) {
+				t.Errorf("expected the replaced blocks %v to be kept as non-canonical, got %v", replaced, got)
+			}
+		})
+	}
+}
+
+func TestRunDecodesEvents(t *testing.T) {
+	store, pool := testStore(t)
+	chain := testchain.New(t)
+	token, err := abi.JSON(strings.NewReader(`[{"type": "event", "name": "Transfer", "inputs": [
+		{"name": "from", "type": "address", "indexed": true},
+		{"name": "to", "type": "address", "indexed": true},
+		{"name": "value", "type": "uint256"}
+	]}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoder, err := NewABIHandler(map[common.Address]abi.ABI{chain.Token: token})
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry, err := NewRegistry(NewERC20TransferHandler(), decoder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 6 {
+		chain.Transfer(alice, int64(i+1))
+		chain.Commit()
+	}
+	fetcher := gateway.NewBlockFetcher(chain.Client, gateway.WithLogFilters(registry.Filters()...))
+	idx := NewIndexer(fetcher, store, WithRegistry(registry))
+	head := chain.Head()
+	runIndexer(t, idx, 1, head)
+
+	chain.Fork(head - 2)
+	for range 3 {
+		chain.Transfer(bob, 100)
+		chain.Commit()
+	}
+	runIndexer(t, idx, head+1, chain.Head())
+	assertCanonical(t, chain, pool)
+
+	// Every canonical transfer is decoded too, and the reorged ones are not canonical anymore.
+	transfers := queryStrings(t, pool, `SELECT concat_ws(' ', tx_hash, log_index, from_address, to_address, value::text)
+		FROM erc20_transfers WHERE is_canonical ORDER BY block_number, log_index`)
+	decoded := queryStrings(t, pool, `SELECT concat_ws(' ', tx_hash, log_index, args->>'from', args->>'to', args->>'value')
+		FROM decoded_events WHERE is_canonical AND event_name = 'Transfer' ORDER BY block_number, log_index`)
+	if !slices.Equal(decoded, transfers) {
+		t.Errorf("canonical decoded events:\n got %v\nwant %v", decoded, transfers)
+	}
+	if got := queryStrings(t, pool, "SELECT count(*)::text FROM decoded_events WHERE NOT is_canonical"); got[0] == "0" {
+		t.Error("expected the events of the reorged blocks to be kept as non-canonical")
+	}
+}
+
+func TestReorgRollsBackUnregisteredHandlers(t *testing.T) {
+	store, pool := testStore(t)
+	chain := testchain.New(t)
+	token, err := abi.JSON(strings.NewReader(`[{"type": "event", "name": "Transfer", "inputs": [
+		{"name": "from", "type": "address", "indexed": true},
+		{"name": "to", "type": "address", "indexed": true},
+		{"name": "value", "type": "uint256"}
+	]}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoder, err := NewABIHandler(map[common.Address]abi.ABI{chain.Token: token})
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry, err := NewRegistry(NewERC20TransferHandler(), decoder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 4 {
+		chain.Transfer(alice, int64(i+1))
+		chain.Commit()
+	}
+	fetcher := gateway.NewBlockFetcher(chain.Client, gateway.WithLogFilters(registry.Filters()...))
+	head := chain.Head()
+	runIndexer(t, NewIndexer(fetcher, store, WithRegistry(registry)), 1, head)
+
+	// Restarted without the ABI handler, the reorg still rolls its rows back.
+	forkPoint := head - 2
+	chain.Fork(forkPoint)
+	for range 3 {
+		chain.Transfer(bob, 100)
+		chain.Commit()
+	}
+	runIndexer(t, NewIndexer(fetcher, store), head+1, chain.Head())
+	assertCanonical(t, chain, pool)
+	got := queryStrings(t, pool, fmt.Sprintf("SELECT count(*)::text FROM decoded_events WHERE is_canonical AND block_number > %d", forkPoint))
+	if got[0] != "0" {
+		t.Errorf("expected the decoded events of the reorged blocks to be non-canonical, got %s canonical", got[0])
+	}
+}
+
+func TestSaveIndexedBlockChecksParent(t *testing.T) {
+	store, pool := testStore(t)
+	chain := testchain.New(t)
+	chain.Transfer(alice, 1)
+	chain.Commit()
+	idx := NewIndexer(gateway.NewBlockFetcher(chain.Client), store)
+	head := chain.Head()
+	runIndexer(t, idx, 1, head)
+
+	// A block on another parent is not written at all, transfers included.
+	err := store.SaveIndexedBlock(context.Background(), storage.IndexedBlock{
+		Block: sqlc.CreateBlockParams{
+			Hash:       common.HexToHash("0x01").String(),
+			Number:     head + 1,
+			ParentHash: common.HexToHash("0x02").String(),
+			Timestamp:  time.Now(),
+		},
+		Events: []storage.EventRows{storage.ERC20TransferRows{{
+			TxHash:       common.HexToHash("0x03").String(),
+			BlockNumber:  head + 1,
+			FromAddress:  alice.Hex(),
+			ToAddress:    bob.Hex(),
+			Value:        pgtype.Numeric{Int: big.NewInt(1), Valid: true},
+			TokenAddress: chain.Token.Hex(),
+		}}},
+	})
+	if !errors.Is(err, storage.ErrParentMismatch) {
+		t.Fatalf("expected ErrParentMismatch, got %v", err)
+	}
+	assertCanonical(t, chain, pool)
+
+	// Nor is a block on a parent rolled back by a reorg.
+	if err := store.MarkBlockReorgedRange(context.Background(), head-1); err != nil {
+		t.Fatal(err)
+	}
+	err = store.SaveIndexedBlock(context.Background(), storage.IndexedBlock{
+		Block: sqlc.CreateBlockParams{
+			Hash:       common.HexToHash("0x01").String(),
+			Number:     head + 1,
+			ParentHash: chain.Header(head).Hash().String(),
+			Timestamp:  time.Now(),
+		},
+	})
+	if !errors.Is(err, storage.ErrParentMismatch) {
+		t.Fatalf("expected ErrParentMismatch on a reorged parent, got %v", err)
+	}
+}
+
+func TestRunBackfill(t *testing.T) {
+	store, pool := testStore(t)
+	chain := testchain.New(t)
+	for i := range 80 {
+		if i%3 == 0 {
+			chain.Transfer(alice, int64(i+1))
+		}
+		chain.Commit()
+	}
+	fetcher := gateway.NewBlockFetcher(chain.Client)
+	finalized, err := fetcher.GetTaggedBlockNumber(context.Background(), rpc.FinalizedBlockNumber)
+	if err != nil {
+		t.Fatalf("Failed to get finalized block: %v", err)
+	}
+	if finalized < 20 {
+		t.Fatalf("expected the simulated chain to have finalized blocks, got %d", finalized)
+	}
+
+	// Finalized blocks are indexed in ranges of 16, the rest one at a time.
+	idx := NewIndexer(fetcher, store, WithBackfill(16, 12))
+	runIndexer(t, idx, 1, chain.Head())
+	assertCanonical(t, chain, pool)
+	if got := queryStrings(t, pool, "SELECT count(*)::text FROM blocks WHERE processed_at IS NULL"); got[0] != "0" {
+		t.Errorf("expected every block to be marked processed, %s are not", got[0])
+	}
+
+	// A fork above the finalized block is still handled per block.
+	head := chain.Head()
+	chain.Fork(head - 2)
+	for range 3 {
+		chain.Transfer(bob, 100)
+		chain.Commit()
+	}
+	runIndexer(t, idx, head+1, chain.Head())
+	assertCanonical(t, chain, pool)
+}
+
+func TestFindCommonAncestor(t *testing.T) {
+	fetcher := replayFetcher(t)
+	store, _ := testStore(t)
+	head, err := fetcher.GetBlockNumber(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx := NewIndexer(fetcher, store)
+	runIndexer(t, idx, 1, int64(head))
+
+	if err := fetcher.Load(forkFixtures); err != nil {
+		t.Fatalf("Failed to load the fork: %v", err)
+	}
+	canonical, err := fetcher.FetchHeader(context.Background(), head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := int64(head) - replayForkDepth
+
+	// The indexer that ran reads saved hashes from its recent block cache, a
+	// fresh one from the database.
+	for name, finder := range map[string]*Indexer{"cached": idx, "database": NewIndexer(fetcher, store)} {
+		ancestor, err := finder.findCommonAncestor(context.Background(), int64(head), canonical.Hash())
+		if err != nil {
+			t.Fatalf("%s: failed to find common ancestor: %v", name, err)
+		}
+		if ancestor != want {
+			t.Errorf("%s: expected common ancestor %d, got %d", name, want, ancestor)
+		}
+	}
+}
+
+func TestRunFinalizer(t *testing.T) {
+	store, pool := testStore(t)
+	chain := testchain.New(t)
+	// The simulated chain finalizes in epochs of 32 blocks, its safe block is the head.
+	for range 40 {
+		chain.Commit()
+	}
+	fetcher := gateway.NewBlockFetcher(chain.Client)
+	idx := NewIndexer(fetcher, store)
+	head := chain.Head()
+	runIndexer(t, idx, 1, head)
+	finalized, err := fetcher.GetTaggedBlockNumber(context.Background(), rpc.FinalizedBlockNumber)
+	if err != nil {
+		t.Fatalf("Failed to get finalized block: %v", err)
+	}
+	if finalized == 0 {
+		t.Fatal("expected the simulated chain to have finalized blocks")
+	}
+
+	var want []string
+	for n := int64(1); n <= head; n++ {
+		status := "SAFE"
+		if n <= int64(finalized) {
+			status = "FINALIZED"
+		}
+		want = append(want, fmt.Sprintf("%d %s", n, status))
+	}
+	idx.finalizerInterval = 10 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- idx.RunFinalizer(ctx, 12) }()
+
+	var got []string
+	for deadline := time.Now().Add(10 * time.Second); time.Now().Before(deadline); time.Sleep(20 * time.Millisecond) {
+		got = queryStrings(t, pool, "SELECT number || ' ' || status FROM blocks WHERE is_canonical ORDER BY number")
+		if slices.Equal(got, want) {
+			break
+		}
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("RunFinalizer failed: %v", err)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("block statuses:\n got %v\nwant %v", got, want)
+	}
+}
+
+func TestReorgResetsBlockStatus(t *testing.T) {
+	store, pool := testStore(t)
+	chain := testchain.New(t)
+	for range 5 {
+		chain.Commit()
+	}
+	idx := NewIndexer(gateway.NewBlockFetcher(chain.Client), store)
+	head := chain.Head()
+	runIndexer(t, idx, 1, head)
+	ctx := context.Background()
+	if err := store.MarkBlockSafe(ctx, head); err != nil {
+		t.Fatal(err)
+	}
+
+	// The rolled back blocks are saved again, here the very same ones.
+	if err := store.MarkBlockReorgedRange(ctx, head-2); err != nil {
+		t.Fatal(err)
+	}
+	runIndexer(t, NewIndexer(gateway.NewBlockFetcher(chain.Client), store), head-1, head)
+	var want []string
+	for n := int64(1); n <= head; n++ {
+		status := "SAFE"
+		if n > head-2 {
+			status = "PENDING"
+		}
+		want = append(want, fmt.Sprintf("%d %s", n, status))
+	}
+	got := queryStrings(t, pool, "SELECT number || ' ' || status FROM blocks WHERE is_canonical ORDER BY number")
+	if !slices.Equal(got, want) {
+		t.Errorf("block statuses:\n got %v\nwant %v", got, want)
+	}
+}
+
+func TestMempoolReconcilesReorgs(t *testing.T) {
+	store, pool := testStore(t)
+	chain := testchain.New(t)
+	fetcher := gateway.NewBlockFetcher(chain.Client)
+	idx := NewIndexer(fetcher, store)
+	runIndexer(t, idx, 1, chain.Head())
+	m := NewMempool(nil, fetcher, store, time.Hour)
+	m.reconcile()
+
+	ctx := context.Background()
+	txHash := chain.Transfer(alice, 1)
+	tx, _, err := chain.Client.TransactionByHash(ctx, txHash)
+	if err != nil {
+		t.Fatalf("Failed to get pending transaction: %v", err)
+	}
+	sender, err := gateway.TxSender(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.SavePendingTransactionBatch(ctx, []sqlc.BatchCreatePendingTransactionParams{pendingTxParams(gateway.PendingTx{Tx: tx, From: sender})})
+	if err != nil {
+		t.Fatalf("Failed to save pending transaction: %v", err)
+	}
+	status := func() string {
+		t.Helper()
+		return queryStrings(t, pool, "SELECT concat_ws(' ', status, block_number) FROM pending_transactions WHERE tx_hash = '"+txHash.String()+"'")[0]
+	}
+	chain.Commit()
+	mined := chain.Head()
+	runIndexer(t, idx, mined, mined)
+	m.reconcile()
+	if want := fmt.Sprintf("CONFIRMED %d", mined); status() != want {
+		t.Fatalf("expected %q, got %q", want, status())
+	}
+
+	// The block is replaced by one mining the transaction again, and the
+	// indexer gets past it before the next reconciliation.
+	chain.Fork(mined - 1)
+	chain.Transfer(bob, 1)
+	chain.Commit()
+	chain.Commit()
+	runIndexer(t, idx, mined+1, chain.Head())
+	m.reconcile()
+	if want := fmt.Sprintf("CONFIRMED %d", mined); status() != want {
+		t.Errorf("expected the re-mined transaction to be %q, got %q", want, status())
+	}
+}
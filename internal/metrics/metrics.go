@@ -31,12 +31,155 @@ var (
 		},
 	)
 
+	HeadSubscriptionActive = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "head_subscription_active",
+			Help: "1 while new heads arrive over a newHeads subscription, 0 while polling",
+		},
+	)
+
+	PendingTxSubscriptionActive = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "pending_tx_subscription_active",
+			Help: "1 while the newPendingTransactions subscription is up, 0 while it is down",
+		},
+	)
+
+	PendingTransactionsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pending_transactions_total",
+			Help: "Mempool transactions by outcome: seen, discarded (reader too slow), confirmed or dropped",
+		},
+		[]string{"status"},
+	)
+
 	RPCErrorsTotal = promauto.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "rpc_errors_total",
 			Help: "Total number of RPC errors encountered",
 		},
-		[]string{"type"}, // e.g., "timeout", "rate_limit", "unknown"
+		[]string{"type"}, // gateway.ErrorClass: "rate_limit", "node_lag", "range_too_large", "transient", "fatal"
+	)
+
+	RPCEndpointRequestsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "rpc_endpoint_requests_total",
+			Help: "Total number of RPC calls per endpoint and result",
+		},
+		[]string{"endpoint", "result"}, // result: "success", "error"
+	)
+
+	RPCEndpointLatency = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "rpc_endpoint_latency_seconds",
+			Help:    "Histogram of RPC call latency per endpoint in seconds",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"endpoint"},
+	)
+
+	RPCEndpointHealthScore = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "rpc_endpoint_health_score",
+			Help: "Health score per RPC endpoint, from 0 (unusable) to 1 (healthy)",
+		},
+		[]string{"endpoint"},
+	)
+
+	RPCEndpointFailoversTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "rpc_endpoint_failovers_total",
+			Help: "Total number of times a call failed over to another RPC endpoint",
+		},
+		[]string{"endpoint"}, // the endpoint failed over to
+	)
+
+	RPCCircuitBreakerState = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "rpc_circuit_breaker_state",
+			Help: "State of the RPC circuit breaker: 0 closed, 1 half-open (probing), 2 open (calls paused)",
+		},
+	)
+
+	RPCMethodDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "rpc_method_duration_seconds",
+			Help:    "Histogram of BlockFetcher call latency per method in seconds, retries included",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"method", "result"}, // result: "success", "error"
+	)
+
+	RPCCacheRequestsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "rpc_cache_requests_total",
+			Help: "Total number of BlockFetcher calls looked up in the response cache per method and result",
+		},
+		[]string{"method", "result"}, // result: "hit", "miss"
+	)
+
+	RPCLogSpan = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "rpc_log_span_blocks",
+			Help: "Learned maximum block span per eth_getLogs call for each RPC endpoint",
+		},
+		[]string{"endpoint"},
+	)
+
+	RPCLogRangeSplitsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "rpc_log_range_splits_total",
+			Help: "Total number of eth_getLogs ranges split because the endpoint rejected them as too large",
+		},
+		[]string{"endpoint"},
+	)
+
+	RPCComputeUnitsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "rpc_compute_units_total",
+			Help: "Total compute units spent per RPC endpoint",
+		},
+		[]string{"endpoint"},
+	)
+
+	RPCComputeUnitBudget = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "rpc_compute_unit_budget_per_second",
+			Help: "Configured compute unit budget per second for each RPC endpoint (0 = unlimited)",
+		},
+		[]string{"endpoint"},
+	)
+
+	RPCRateLimitWaitSeconds = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "rpc_rate_limit_wait_seconds_total",
+			Help: "Total time spent waiting for the client-side rate limiter per RPC endpoint",
+		},
+		[]string{"endpoint"},
+	)
+
+	QuorumDiscrepanciesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "quorum_discrepancies_total",
+			Help: "Total number of blocks on which verifier providers disagreed with the primary",
+		},
+		[]string{"kind"}, // "block_hash", "log_count"
+	)
+
+	LogVerificationFailuresTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "log_verification_failures_total",
+			Help: "Total number of blocks whose logs or receipts did not match the block header",
+		},
+		[]string{"reason"}, // "block_hash", "bloom", "receipts_root", "log_set"
+	)
+
+	RecentBlockCacheRequestsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "recent_block_cache_requests_total",
+			Help: "Total number of saved block lookups served from the in-memory recent block cache, by result",
+		},
+		[]string{"result"}, // "hit", "miss"
 	)
 
 	ReorgDetectedTotal = promauto.NewCounter(
@@ -60,6 +203,58 @@ var (
 			Buckets: prometheus.DefBuckets, // Default buckets: 0.005s, 0.01s, 0.025s, ... 10s
 		},
 	)
+
+	BlocksFetchedTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "blocks_fetched_total",
+			Help: "Total number of blocks fetched by the prefetch pipeline, refetches included",
+		},
+	)
+
+	PipelineBufferedBlocks = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "pipeline_buffered_blocks",
+			Help: "Blocks fetched ahead by the prefetch pipeline and waiting to be saved",
+		},
+	)
+
+	PipelineFetchDuration = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "pipeline_window_fetch_duration_seconds",
+			Help:    "Histogram of the time a fetch worker takes to fetch one window of blocks",
+			Buckets: prometheus.ExponentialBuckets(0.1, 2, 10), // 0.1s to ~51s
+		},
+	)
+
+	PipelineCommitWaitSeconds = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "pipeline_commit_wait_seconds_total",
+			Help: "Total time spent waiting for the prefetch pipeline before saving a block; growing means fetching is the bottleneck",
+		},
+	)
+
+	IndexedEventsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "indexed_events_total",
+			Help: "Total number of rows indexed by each event handler",
+		},
+		[]string{"handler"},
+	)
+
+	BackfillBlocksTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "backfill_blocks_total",
+			Help: "Total number of finalized blocks indexed in bulk by range-based backfill",
+		},
+	)
+
+	BackfillRangeDuration = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "backfill_range_duration_seconds",
+			Help:    "Histogram of the time taken to fetch and save one backfill range",
+			Buckets: prometheus.ExponentialBuckets(0.1, 2, 10), // 0.1s to ~51s
+		},
+	)
 )
 
 // InitMetricsServer starts the Prometheus metrics HTTP server on the given address
@@ -3,12 +3,14 @@ package storage
 import (
 	"context"
 	"errors"
-	"log/slog"
+	"fmt"
+	"time"
 
 	"github.com/KhanSufiyanMirza/evm-indexer-go/db/sqlc"
 	"github.com/cenkalti/backoff/v5"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type Store struct {
@@ -23,63 +25,110 @@ func NewStore(store sqlc.Store) *Store {
 
 var (
 	ErrBlockNotFound = errors.New("block not found")
+	// ErrChainMismatch is returned when the database holds another chain
+	// than the one the RPC endpoints serve.
+	ErrChainMismatch = errors.New("database is bound to another chain")
+	// ErrParentMismatch is returned when the block saved below a new block is
+	// not its parent, because the chain was rolled back or rewritten since
+	// the caller checked.
+	ErrParentMismatch = errors.New("saved block is not the parent")
 )
 
-// SaveBlock attempts to insert a block.
-// If the block already exists, ON CONFLICT (hash, number) DO UPDATE re-canonicalizes it
-// so re-insert after reorg marks the row is_canonical = TRUE.
-func (s *Store) SaveBlock(ctx context.Context, params sqlc.CreateBlockParams) error {
-	// uncomment count and log line to see retry attempts and error
-	// count := 0
-	_, err := retry(ctx, func() (sqlc.CreateBlockRow, error) {
-		res, err := s.CreateBlock(ctx, params)
-		if err != nil {
-			// count++
-			// log.Printf("Error inserting block %d, attempt %d: %v", params.Number, count, err)
-			if errors.Is(err, pgx.ErrNoRows) {
-				slog.Debug("Block already exists (idempotent skip)", "block", params.Number)
-				return sqlc.CreateBlockRow{}, nil
+// EventRows are the rows an event handler decoded from logs, saved within the
+// transaction saving their blocks.
+type EventRows interface {
+	// Len is how many rows there are.
+	Len() int
+	Save(ctx context.Context, q *sqlc.Queries) error
+}
+
+// ERC20TransferRows are ERC20 transfers, saved in a single batch round-trip.
+// A transfer that already exists is overwritten and re-canonicalized, a
+// transaction re-mined after a reorg may have moved to another block.
+type ERC20TransferRows []sqlc.BatchCreateERC20TransferParams
+
+func (r ERC20TransferRows) Len() int {
+	return len(r)
+}
+
+func (r ERC20TransferRows) Save(ctx context.Context, q *sqlc.Queries) error {
+	if len(r) == 0 {
+		return nil
+	}
+	return execBatch(q.BatchCreateERC20Transfer(ctx, r))
+}
+
+// DecodedEventRows are events decoded with contract ABIs, saved like
+// ERC20TransferRows.
+type DecodedEventRows []sqlc.BatchCreateDecodedEventParams
+
+func (r DecodedEventRows) Len() int {
+	return len(r)
+}
+
+func (r DecodedEventRows) Save(ctx context.Context, q *sqlc.Queries) error {
+	if len(r) == 0 {
+		return nil
+	}
+	return execBatch(q.BatchCreateDecodedEvent(ctx, r))
+}
+
+// IndexedBlock is a block with everything indexed for it, saved at once by
+// SaveIndexedBlock.
+type IndexedBlock struct {
+	Block             sqlc.CreateBlockParams
+	Events            []EventRows
+	Receipts          []sqlc.BatchCreateReceiptParams
+	InternalTransfers []sqlc.BatchCreateInternalTransferParams
+}
+
+// SavePendingTransactionBatch inserts transactions seen in the mempool in a
+// single batch round-trip. A transaction seen again keeps its first sighting.
+func (s *Store) SavePendingTransactionBatch(ctx context.Context, params []sqlc.BatchCreatePendingTransactionParams) error {
+	if len(params) == 0 {
+		return nil
+	}
+	_, err := retry(ctx, func() (bool, error) {
+		batchResults := s.BatchCreatePendingTransaction(ctx, params)
+		var batchErr error
+		batchResults.Exec(func(i int, err error) {
+			if err != nil {
+				batchErr = err
 			}
-			if isConstraintViolation(err) {
-				return sqlc.CreateBlockRow{}, backoff.Permanent(err)
+		})
+		if batchErr != nil {
+			if isConstraintViolation(batchErr) {
+				return false, backoff.Permanent(batchErr)
 			}
-			return sqlc.CreateBlockRow{}, err
+			return false, batchErr
 		}
-		return res, nil
+		return true, nil
 	})
 	return err
 }
 
-func (s *Store) SaveERC20Transfer(ctx context.Context, params sqlc.CreateERC20TransferParams) error {
-	// uncomment count and log line to see retry attempts and error
-	// count := 0
-	_, err := retry(ctx, func() (sqlc.CreateERC20TransferRow, error) {
-		res, err := s.CreateERC20Transfer(ctx, params)
-		if err != nil {
-			// count++
-			// log.Printf("Error inserting ERC20 Transfer %s-%d, attempt %d: %v", params.TxHash, params.LogIndex, count, err)
-			if errors.Is(err, pgx.ErrNoRows) {
-				slog.Debug("ERC20 transfer already exists (idempotent skip)", "txHash", params.TxHash, "logIndex", params.LogIndex)
-				return sqlc.CreateERC20TransferRow{}, nil
-			}
-			if isConstraintViolation(err) {
-				return sqlc.CreateERC20TransferRow{}, backoff.Permanent(err)
-			}
-			return sqlc.CreateERC20TransferRow{}, err
-		}
-		return res, nil
+// ConfirmPendingTransactions marks the pending transactions among txHashes as
+// mined in blockNumber and returns how many there were.
+func (s *Store) ConfirmPendingTransactions(ctx context.Context, blockNumber int64, txHashes []string) (int64, error) {
+	if len(txHashes) == 0 {
+		return 0, nil
+	}
+	return retry(ctx, func() (int64, error) {
+		return s.Store.ConfirmPendingTransactions(ctx, sqlc.ConfirmPendingTransactionsParams{
+			BlockNumber: pgtype.Int8{Int64: blockNumber, Valid: true},
+			TxHashes:    txHashes,
+		})
 	})
-	return err
 }
 
-// SaveERC20TransferBatch inserts multiple ERC20 transfers in a single batch round-trip.
-// Each individual insert uses ON CONFLICT DO NOTHING for idempotency.
-func (s *Store) SaveERC20TransferBatch(ctx context.Context, params []sqlc.BatchCreateERC20TransferParams) error {
+// ReplacePendingTransactionBatch marks pending transactions whose sender and
+// nonce were used by another transaction mined in a block as replaced by it.
+func (s *Store) ReplacePendingTransactionBatch(ctx context.Context, params []sqlc.BatchReplacePendingTransactionParams) error {
 	if len(params) == 0 {
 		return nil
 	}
 	_, err := retry(ctx, func() (bool, error) {
-		batchResults := s.BatchCreateERC20Transfer(ctx, params)
+		batchResults := s.BatchReplacePendingTransaction(ctx, params)
 		var batchErr error
 		batchResults.Exec(func(i int, err error) {
 			if err != nil {
@@ -87,9 +136,6 @@ func (s *Store) SaveERC20TransferBatch(ctx context.Context, params []sqlc.BatchC
 			}
 		})
 		if batchErr != nil {
-			if isConstraintViolation(batchErr) {
-				return false, backoff.Permanent(batchErr)
-			}
 			return false, batchErr
 		}
 		return true, nil
@@ -97,10 +143,144 @@ func (s *Store) SaveERC20TransferBatch(ctx context.Context, params []sqlc.BatchC
 	return err
 }
 
-func (s *Store) MarkBlockProcessed(ctx context.Context, blockNumber int64) error {
+// DropStalePendingTransactions marks transactions pending for longer than
+// timeout as dropped and returns how many there were.
+func (s *Store) DropStalePendingTransactions(ctx context.Context, timeout time.Duration) (int64, error) {
+	return retry(ctx, func() (int64, error) {
+		return s.Store.DropStalePendingTransactions(ctx, pgtype.Interval{Microseconds: timeout.Microseconds(), Valid: true})
+	})
+}
+
+// BindChain binds the database to the chain with chainID and genesisHash on
+// its first run, reporting true, and otherwise checks that it is the chain the
+// database was bound to. Chain IDs alone are not unique (forks and devnets
+// reuse them), the genesis hash is.
+func (s *Store) BindChain(ctx context.Context, chainID int64, genesisHash string) (bool, error) {
+	created, err := retry(ctx, func() (int64, error) {
+		return s.CreateChainMetadata(ctx, sqlc.CreateChainMetadataParams{
+			ChainID:     chainID,
+			GenesisHash: genesisHash,
+		})
+	})
+	if err != nil {
+		return false, err
+	}
+	if created > 0 {
+		return true, nil
+	}
+	bound, err := retry(ctx, func() (sqlc.GetChainMetadataRow, error) {
+		return s.GetChainMetadata(ctx)
+	})
+	if err != nil {
+		return false, err
+	}
+	if bound.ChainID != chainID || bound.GenesisHash != genesisHash {
+		return false, fmt.Errorf("%w: bound to chain %d (genesis %s) but the RPC serves chain %d (genesis %s)",
+			ErrChainMismatch, bound.ChainID, bound.GenesisHash, chainID, genesisHash)
+	}
+	return false, nil
+}
+
+// SaveBlockDiscrepancy records providers disagreeing about a block.
+func (s *Store) SaveBlockDiscrepancy(ctx context.Context, params sqlc.CreateBlockDiscrepancyParams) error {
+	_, err := retry(ctx, func() (bool, error) {
+		err := s.CreateBlockDiscrepancy(ctx, params)
+		if err != nil {
+			if isConstraintViolation(err) {
+				return false, backoff.Permanent(err)
+			}
+			return false, err
+		}
+		return true, nil
+	})
+	return err
+}
+
+// SaveIndexedBlock inserts a block with its event rows, receipts and internal
+// transfers and marks it processed, all in one transaction, so a
+// block is either fully indexed or not at all. The saved block below it is
+// locked and checked to be its parent first; when it is not,
+// ErrParentMismatch is returned and nothing is written, as it is when no
+// canonical block is saved below it but older ones are: it would be saved on
+// top of a gap or of a parent rolled back by a reorg. Only the first block
+// indexed has nothing to be checked against.
+func (s *Store) SaveIndexedBlock(ctx context.Context, b IndexedBlock) error {
+	_, err := retry(ctx, func() (bool, error) {
+		err := s.Store.ExecTx(ctx, func(querier *sqlc.Queries) error {
+			parentHash, err := querier.LockCanonicalBlock(ctx, b.Block.Number-1)
+			switch {
+			case errors.Is(err, pgx.ErrNoRows):
+				older, err := querier.HasCanonicalBlockBelow(ctx, b.Block.Number-1)
+				if err != nil {
+					return err
+				}
+				if older {
+					return fmt.Errorf("%w: block %d has no saved parent", ErrParentMismatch, b.Block.Number)
+				}
+			case err != nil:
+				return err
+			case parentHash != b.Block.ParentHash:
+				return fmt.Errorf("%w: block %d has parent %s, saved block is %s", ErrParentMismatch, b.Block.Number, b.Block.ParentHash, parentHash)
+			}
+
+			if _, err := querier.CreateBlock(ctx, b.Block); err != nil {
+				return err
+			}
+			for _, events := range b.Events {
+				if err := events.Save(ctx, querier); err != nil {
+					return err
+				}
+			}
+			if len(b.Receipts) > 0 {
+				if err := execBatch(querier.BatchCreateReceipt(ctx, b.Receipts)); err != nil {
+					return err
+				}
+			}
+			if len(b.InternalTransfers) > 0 {
+				if err := execBatch(querier.BatchCreateInternalTransfer(ctx, b.InternalTransfers)); err != nil {
+					return err
+				}
+			}
+			return querier.MarkBlockProcessed(ctx, b.Block.Number)
+		})
+		if err != nil {
+			if errors.Is(err, ErrParentMismatch) || isConstraintViolation(err) {
+				return false, backoff.Permanent(err)
+			}
+			return false, err
+		}
+		return true, nil
+	})
+	return err
+}
+
+// SaveBlockRange inserts a consecutive range of blocks with their event rows
+// and marks them processed, all in one transaction, so a range is either
+// fully indexed or not at all. Used for backfilling finalized blocks, which
+// are not checked for reorgs one by one.
+func (s *Store) SaveBlockRange(ctx context.Context, blocks []sqlc.BatchCreateBlockParams, events []EventRows) error {
+	if len(blocks) == 0 {
+		return nil
+	}
 	_, err := retry(ctx, func() (bool, error) {
-		err := s.Store.MarkBlockProcessed(ctx, blockNumber)
+		err := s.Store.ExecTx(ctx, func(querier *sqlc.Queries) error {
+			if err := execBatch(querier.BatchCreateBlock(ctx, blocks)); err != nil {
+				return err
+			}
+			for _, rows := range events {
+				if err := rows.Save(ctx, querier); err != nil {
+					return err
+				}
+			}
+			return querier.MarkBlockProcessedRange(ctx, sqlc.MarkBlockProcessedRangeParams{
+				FromBlock: blocks[0].Number,
+				ToBlock:   blocks[len(blocks)-1].Number,
+			})
+		})
 		if err != nil {
+			if isConstraintViolation(err) {
+				return false, backoff.Permanent(err)
+			}
 			return false, err
 		}
 		return true, nil
@@ -108,6 +288,17 @@ func (s *Store) MarkBlockProcessed(ctx context.Context, blockNumber int64) error
 	return err
 }
 
+// execBatch runs every statement of a batch, returning the last error.
+func execBatch(results interface{ Exec(func(int, error)) }) error {
+	var batchErr error
+	results.Exec(func(i int, err error) {
+		if err != nil {
+			batchErr = err
+		}
+	})
+	return batchErr
+}
+
 func (s *Store) MarkBlockFinalized(ctx context.Context, blockNumber int64) error {
 	_, err := retry(ctx, func() (bool, error) {
 		err := s.Store.MarkBlockFinalized(ctx, blockNumber)
@@ -119,6 +310,17 @@ func (s *Store) MarkBlockFinalized(ctx context.Context, blockNumber int64) error
 	return err
 }
 
+func (s *Store) MarkBlockSafe(ctx context.Context, blockNumber int64) error {
+	_, err := retry(ctx, func() (bool, error) {
+		err := s.Store.MarkBlockSafe(ctx, blockNumber)
+		if err != nil {
+			return false, err
+		}
+		return true, nil
+	})
+	return err
+}
+
 func (s *Store) GetLatestBlockNumber(ctx context.Context) (int64, error) {
 	return retry(ctx, func() (int64, error) {
 		blockNo, err := s.Store.GetLatestBlockNumber(ctx)
@@ -138,6 +340,13 @@ func (s *Store) GetBlockByNumber(ctx context.Context, blockNumber int64) (sqlc.G
 	})
 }
 
+// ListRecentBlocks returns up to limit of the highest canonical blocks, highest first.
+func (s *Store) ListRecentBlocks(ctx context.Context, limit int32) ([]sqlc.ListBlocksRow, error) {
+	return retry(ctx, func() ([]sqlc.ListBlocksRow, error) {
+		return s.Store.ListBlocks(ctx, sqlc.ListBlocksParams{Limit: limit})
+	})
+}
+
 func (s *Store) GetLatestProcessedBlockNumber(ctx context.Context) (int64, error) {
 	return retry(ctx, func() (int64, error) {
 		blockNo, err := s.Store.GetLatestProcessedBlockNumber(ctx)
@@ -168,17 +377,34 @@ func isConstraintViolation(err error) bool {
 
 func (s *Store) DeleteBlockRange(ctx context.Context, fromBlock int64) error {
 	// We delete in reverse order of dependencies:
-	// 1. ERC20 Transfers (refer to blocks)
+	// 1. ERC20 Transfers, decoded events, receipts and internal transfers (refer to blocks)
 	// 2. Blocks
+	// Pending transactions mined in the removed blocks go back to pending.
 	// Note: If you have more tables, add them here.
 
-	// 1. Delete ERC20 Transfers
+	// 1. Delete ERC20 Transfers, decoded events, receipts and internal transfers
 	_, err := retry(ctx, func() (bool, error) {
 		err := s.Store.ExecTx(ctx, func(querier *sqlc.Queries) error {
 			err := querier.DeleteERC20TransfersFromHeight(ctx, fromBlock)
 			if err != nil {
 				return err
 			}
+			err = querier.DeleteDecodedEventsFromHeight(ctx, fromBlock)
+			if err != nil {
+				return err
+			}
+			err = querier.DeleteReceiptsFromHeight(ctx, fromBlock)
+			if err != nil {
+				return err
+			}
+			err = querier.DeleteInternalTransfersFromHeight(ctx, fromBlock)
+			if err != nil {
+				return err
+			}
+			err = querier.ResetPendingTransactionsFromHeight(ctx, pgtype.Int8{Int64: fromBlock, Valid: true})
+			if err != nil {
+				return err
+			}
 
 			err = querier.DeleteBlocksFromHeight(ctx, fromBlock)
 			return err
@@ -191,13 +417,21 @@ func (s *Store) DeleteBlockRange(ctx context.Context, fromBlock int64) error {
 
 	return err
 }
+
+// MarkBlockReorgedRange marks everything above fromBlock as non-canonical,
+// and the blocks PENDING again: a block saved anew at the same height, or
+// the same block mined again, is promoted by the finalizer from scratch.
+// The tables of event handlers are all marked, whether their handlers are
+// registered or not, rows saved before a registry change must not stay
+// canonical.
 func (s *Store) MarkBlockReorgedRange(ctx context.Context, fromBlock int64) error {
 	// We mark in reverse order of dependencies:
-	// 1. ERC20 Transfers (refer to blocks)
+	// 1. ERC20 transfers, decoded events, receipts and internal transfers (refer to blocks)
 	// 2. Blocks
+	// Pending transactions mined in the reorged blocks go back to pending.
 	// Note: If you have more tables, add them here.
 
-	// 1. Mark ERC20 Transfers
+	// 1. Mark ERC20 transfers, decoded events, receipts and internal transfers
 	_, err := retry(ctx, func() (bool, error) {
 		err := s.Store.ExecTx(ctx, func(querier *sqlc.Queries) error {
 			err := querier.MarkBlockReorgedRange(ctx, fromBlock)
@@ -206,6 +440,26 @@ func (s *Store) MarkBlockReorgedRange(ctx context.Context, fromBlock int64) erro
 			}
 
 			err = querier.MarkERC20TransfersReorgedRange(ctx, fromBlock)
+			if err != nil {
+				return err
+			}
+
+			err = querier.MarkDecodedEventsReorgedRange(ctx, fromBlock)
+			if err != nil {
+				return err
+			}
+
+			err = querier.MarkReceiptsReorgedRange(ctx, fromBlock)
+			if err != nil {
+				return err
+			}
+
+			err = querier.MarkInternalTransfersReorgedRange(ctx, fromBlock)
+			if err != nil {
+				return err
+			}
+
+			err = querier.ResetPendingTransactionsFromHeight(ctx, pgtype.Int8{Int64: fromBlock, Valid: true})
 			return err
 		})
 		if err != nil {
@@ -0,0 +1,176 @@
+// Package testchain runs a simulated chain with a deployed ERC20 for the
+// tests that need a real node.
+package testchain
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	"github.com/ethereum/go-ethereum/node"
+)
+
+// tokenCode deploys a minimal ERC20: the constructor mints 2^64-1 tokens to
+// the deployer, transfer(address,uint256) and balanceOf(address) behave as
+// usual and both emit standard Transfer events. Balances are stored at the
+// holder's address. Hand-assembled, the runtime code is
+//
+//	selector := calldata[0:4]
+//	if selector == transfer  { goto transfer }
+//	if selector == balanceOf { goto balanceOf }
+//	revert:    revert(0, 0)
+//	balanceOf: return sload(calldata[4:36])
+//	transfer:  if sload(caller) < amount { goto revert }
+//	           sstore(caller, sload(caller) - amount)
+//	           sstore(to, sload(to) + amount)
+//	           log3(amount, Transfer, caller, to)
+//	           return true
+const tokenCode = "67ffffffffffffffff8033556000523360007fddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef60206000a3607e8060436000396000f3" +
+	"60003560e01c8063a9059cbb14602c57806370a0823114601f575b600080fd5b6004355460005260206000f35b6024353354818110601a57818103335550600435805482019055600052600435337fddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef60206000a3600160005260206000f3"
+
+// simulatedChainID is the chain ID of every simulated backend.
+var simulatedChainID = big.NewInt(1337)
+
+// Chain is a simulated chain with a funded account that deployed an ERC20.
+type Chain struct {
+	// Client is a full client of the chain's node.
+	Client *ethclient.Client
+	// Token is the address of the ERC20.
+	Token common.Address
+
+	t       testing.TB
+	backend *simulated.Backend
+	key     *ecdsa.PrivateKey
+	sender  common.Address
+}
+
+// New starts a chain that is stopped when t ends. Its first block deploys
+// the token.
+func New(t testing.TB) *Chain {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	// The backend only hands out a restricted client while the gateway needs
+	// the full one (batch calls, raw RPC), so the node is served over IPC.
+	// Socket paths are short-lived and length-limited, hence not t.TempDir.
+	dir, err := os.MkdirTemp("", "simchain")
+	if err != nil {
+		t.Fatalf("Failed to create IPC dir: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	ipcPath := filepath.Join(dir, "geth.ipc")
+	backend := simulated.NewBackend(types.GenesisAlloc{
+		sender: {Balance: new(big.Int).Lsh(big.NewInt(1), 100)},
+	}, func(nodeConf *node.Config, _ *ethconfig.Config) {
+		nodeConf.IPCPath = ipcPath
+	})
+	t.Cleanup(func() { backend.Close() })
+	client, err := ethclient.Dial(ipcPath)
+	if err != nil {
+		t.Fatalf("Failed to dial simulated chain: %v", err)
+	}
+	t.Cleanup(client.Close)
+	c := &Chain{
+		Client:  client,
+		t:       t,
+		backend: backend,
+		key:     key,
+		sender:  sender,
+	}
+
+	deploy := c.Send(nil, common.FromHex(tokenCode))
+	c.Commit()
+	receipt, err := c.Client.TransactionReceipt(context.Background(), deploy)
+	if err != nil {
+		t.Fatalf("Failed to get deployment receipt: %v", err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		t.Fatal("token deployment failed")
+	}
+	c.Token = receipt.ContractAddress
+	return c
+}
+
+// Send signs and submits a transaction from the funded account, to be mined
+// by the next commit.
+func (c *Chain) Send(to *common.Address, data []byte) common.Hash {
+	c.t.Helper()
+	ctx := context.Background()
+	nonce, err := c.Client.PendingNonceAt(ctx, c.sender)
+	if err != nil {
+		c.t.Fatalf("Failed to get nonce: %v", err)
+	}
+	head, err := c.Client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		c.t.Fatalf("Failed to get head: %v", err)
+	}
+	tip := big.NewInt(1_000_000_000)
+	tx := types.MustSignNewTx(c.key, types.LatestSignerForChainID(simulatedChainID), &types.DynamicFeeTx{
+		ChainID:   simulatedChainID,
+		Nonce:     nonce,
+		GasTipCap: tip,
+		GasFeeCap: new(big.Int).Add(new(big.Int).Mul(head.BaseFee, big.NewInt(2)), tip),
+		Gas:       1_000_000,
+		To:        to,
+		Data:      data,
+	})
+	if err := c.Client.SendTransaction(ctx, tx); err != nil {
+		c.t.Fatalf("Failed to send transaction: %v", err)
+	}
+	return tx.Hash()
+}
+
+// Transfer sends amount tokens to to, to be mined by the next commit.
+func (c *Chain) Transfer(to common.Address, amount int64) common.Hash {
+	c.t.Helper()
+	data := append(common.FromHex("a9059cbb"), common.LeftPadBytes(to.Bytes(), 32)...)
+	data = append(data, common.LeftPadBytes(big.NewInt(amount).Bytes(), 32)...)
+	return c.Send(&c.Token, data)
+}
+
+// Commit mines the pending transactions into a new block.
+func (c *Chain) Commit() {
+	c.backend.Commit()
+}
+
+// Head returns the number of the canonical head.
+func (c *Chain) Head() int64 {
+	c.t.Helper()
+	n, err := c.Client.BlockNumber(context.Background())
+	if err != nil {
+		c.t.Fatalf("Failed to get block number: %v", err)
+	}
+	return int64(n)
+}
+
+// Header returns the canonical header at number.
+func (c *Chain) Header(number int64) *types.Header {
+	c.t.Helper()
+	h, err := c.Client.HeaderByNumber(context.Background(), big.NewInt(number))
+	if err != nil {
+		c.t.Fatalf("Failed to get header %d: %v", number, err)
+	}
+	return h
+}
+
+// Fork rewinds the canonical chain to block number, the blocks committed from
+// then on replace the ones above it. Transactions of the dropped blocks go
+// back to the pool and are mined again by the next commit.
+func (c *Chain) Fork(number int64) {
+	c.t.Helper()
+	if err := c.backend.Fork(c.Header(number).Hash()); err != nil {
+		c.t.Fatalf("Failed to fork at block %d: %v", number, err)
+	}
+}